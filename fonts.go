@@ -1,21 +1,66 @@
 package lookup
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"image"
-	"io/ioutil"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
 	"math"
 	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 )
 
+// FontWeight is the weight (boldness) a FontSymbol was rendered with. It allows symbols of
+// different weights to live in the same font family, while still reporting which one matched.
+type FontWeight int
+
+const (
+	FontWeightRegular FontWeight = iota
+	FontWeightBold
+)
+
+func (w FontWeight) String() string {
+	switch w {
+	case FontWeightRegular:
+		return "regular"
+	case FontWeightBold:
+		return "bold"
+	}
+	return fmt.Sprintf("FontWeight(%d)", int(w))
+}
+
 type FontSymbol struct {
 	symbol  string
 	image   *imageBinary
 	width   int
 	height  int
 	advance int
+	weight  FontWeight
+	italic  bool
+	family  string
+	rtl     bool
+
+	// originX and originY are where the glyph starts inside the image, skipping its padding
+	originX, originY int
+
+	// minScore, when greater than zero, replaces the threshold of the OCR for this symbol
+	minScore float64
+
+	// base is the symbol a variant was created from. Is nil for symbols that are not variants
+	base *FontSymbol
+	// mirrored is set in variants created from a horizontally flipped image of the original symbol
+	mirrored bool
 }
 
 // NewFontSymbolRune creates a new symbol for a rune. opts are optional (if set to nil).
@@ -27,19 +72,44 @@ func NewFontSymbol(symbol string, img image.Image) *FontSymbol {
 	return NewFontSymbolOpts(symbol, img, nil)
 }
 
+// NewFontSymbolWithMetrics creates a new symbol that advances the given number of pixels, which
+// can be more or less than the width of the image. An advance of zero advances the width of the
+// image. See SetAdvance.
+func NewFontSymbolWithMetrics(symbol string, img image.Image, advance int) *FontSymbol {
+	return NewFontSymbolOpts(symbol, img, &NewFontSymbolOptions{Advance: advance})
+}
+
+// NewFontSymbolAlpha creates a new symbol from an image drawn over a transparent background, like
+// white text on a transparent PNG. Transparent pixels are background whatever their color. See
+// NewFontSymbolOptions.Alpha.
+func NewFontSymbolAlpha(symbol string, img image.Image) *FontSymbol {
+	return NewFontSymbolOpts(symbol, img, &NewFontSymbolOptions{Alpha: true})
+}
+
 // NewFontSymbolOpts creates a new symbol for a rune. Use NewFontSymbol for using the default options.
 func NewFontSymbolOpts(symbol string, img image.Image, opts *NewFontSymbolOptions) *FontSymbol {
-	imgBin := newImageBinary(ensureGrayScale(img))
-	advance := math.MaxInt
-	if opts != nil {
-		advance = opts.Advance
+	var gray image.Image
+	if opts != nil && opts.Alpha {
+		gray = alphaToGray(img)
+	} else {
+		gray = grayScale(img, opts != nil && opts.AlphaOnly)
 	}
+	imgBin := symbolBinaries.binary(gray.(*image.Gray))
 	fs := &FontSymbol{
 		symbol:  symbol,
 		image:   imgBin,
 		width:   imgBin.width,
 		height:  imgBin.height,
-		advance: advance,
+		advance: math.MaxInt,
+	}
+	if opts != nil {
+		if opts.Advance > 0 {
+			fs.advance = opts.Advance
+		}
+		fs.weight = opts.Weight
+		fs.italic = opts.Italic
+		fs.rtl = opts.RTL
+		fs.originX, fs.originY = opts.Origin.X, opts.Origin.Y
 	}
 
 	return fs
@@ -52,13 +122,153 @@ func (f FontSymbol) Advance() int {
 	return f.advance
 }
 
+// SetAdvance sets the distance, in pixels, from the start of the symbol to the start of the next
+// one. Narrow symbols of proportional fonts, like 'i', usually advance further than the width of
+// their image, and without it the gap to the next symbol is taken as a space. Setting it to zero
+// makes the symbol advance its width again. Returns an error, keeping the advance unchanged, if
+// advance is negative.
+func (f *FontSymbol) SetAdvance(advance int) error {
+	if advance < 0 {
+		return fmt.Errorf("invalid advance %d for symbol %q", advance, f.symbol)
+	}
+	if advance == 0 {
+		advance = math.MaxInt
+	}
+	f.advance = advance
+	return nil
+}
+
+// variant creates a copy of the symbol, with all its attributes, but using a different image
+func (f *FontSymbol) variant(img image.Image) *FontSymbol {
+	v := *f
+	v.base = f.original()
+	v.image = newImageBinary(img)
+	v.width = v.image.width
+	v.height = v.image.height
+	return &v
+}
+
+// scaled creates a variant of the symbol resized by the given factors, including its advance
+func (f *FontSymbol) scaled(sx, sy float64) *FontSymbol {
+	if sx == 1 && sy == 1 {
+		return f
+	}
+	v := f.variant(scale(f.image.gray(), sx, sy))
+	if f.advance != math.MaxInt {
+		v.advance = max(int(math.Round(float64(f.advance)*sx)), 1)
+	}
+	v.originX = int(math.Round(float64(f.originX) * sx))
+	v.originY = int(math.Round(float64(f.originY) * sy))
+	return v
+}
+
+// shifted creates a variant of the symbol moved by the given fraction of pixels
+func (f *FontSymbol) shifted(dx, dy float64) *FontSymbol {
+	if dx == 0 && dy == 0 {
+		return f
+	}
+	return f.variant(shift(f.image.gray(), dx, dy))
+}
+
+// original returns the symbol this one is a variant of, or itself if it is not a variant
+func (f *FontSymbol) original() *FontSymbol {
+	if f.base != nil {
+		return f.base
+	}
+	return f
+}
+
+// mirror creates a variant of the symbol using its horizontally flipped image
+func (f *FontSymbol) mirror() *FontSymbol {
+	v := f.variant(flipHorizontal(f.image.gray()))
+	v.mirrored = !f.mirrored
+	return v
+}
+
+// hash is a hash of the label and image of the symbol. Identical symbols have the same hash
+func (f *FontSymbol) hash() uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(f.symbol))
+	_, _ = fmt.Fprintf(h, "\x00%dx%d\x00", f.width, f.height)
+	_, _ = h.Write(f.image.gray().Pix)
+	return h.Sum64()
+}
+
+// identical checks if both symbols have the same label and image
+func (f *FontSymbol) identical(other *FontSymbol) bool {
+	return f.symbol == other.symbol && f.width == other.width && f.height == other.height &&
+		bytes.Equal(f.image.gray().Pix, other.image.gray().Pix)
+}
+
+// SetMinScore sets the minimum score the symbol needs to be recognized, replacing the threshold
+// of the OCR for it. Use a stricter value for symbols easily confused with others, like '0' and
+// 'O', without raising the bar for every symbol. Setting it to zero uses the threshold of the OCR.
+func (f *FontSymbol) SetMinScore(minScore float64) { f.minScore = minScore }
+
+// MinScore returns the minimum score set with SetMinScore, or zero if the symbol uses the
+// threshold of the OCR.
+func (f FontSymbol) MinScore() float64 { return f.minScore }
+
+// Family returns the name of the font family the symbol was loaded in, by the LoadFont methods
+// of an OCR or from a font pack, or an empty string otherwise. AddFontFamily doesn't change it, as
+// symbols can be shared by several OCRs: the family the symbol was added to in an OCR is the one
+// reported in the matches of that OCR.
+func (f FontSymbol) Family() string { return f.family }
+
+// SetRTL marks the symbol as belonging to a right-to-left script, like Arabic or Hebrew. Runs of
+// adjacent right-to-left symbols in a line are written in the text in reverse order, so they are
+// read in their logical order, while the rest of the line keeps the left-to-right order.
+func (f *FontSymbol) SetRTL(rtl bool) { f.rtl = rtl }
+
+// RTL returns whether the symbol belongs to a right-to-left script.
+func (f FontSymbol) RTL() bool { return f.rtl }
+
+// SetOrigin sets where the glyph starts inside the image of the symbol, for images with some
+// padding at their top or left. The symbols found are compared from their origin when removing
+// the overlapping ones and sorting them in reading order, so glyphs with padding don't eat or
+// get ahead of their neighbours. The advance, if set, is also measured from the origin.
+func (f *FontSymbol) SetOrigin(x, y int) { f.originX, f.originY = x, y }
+
+// Origin returns where the glyph starts inside the image of the symbol. See SetOrigin.
+func (f FontSymbol) Origin() image.Point { return image.Pt(f.originX, f.originY) }
+
+// Weight returns the FontWeight this symbol was rendered with.
+func (f FontSymbol) Weight() FontWeight { return f.weight }
+
+// Italic returns whether the symbol was rendered with an italic font.
+func (f FontSymbol) Italic() bool { return f.italic }
+
 func (f *FontSymbol) String() string { return f.symbol }
 
 type NewFontSymbolOptions struct {
 	// The advance of the symbol, taken into account when recognizing texts./
 	// This allows symbols to be closer/further away than the width of the symbol.
-	// Is ignored when set to math.MaxInt
+	// Is ignored when not greater than zero or set to math.MaxInt, so the symbol advances its
+	// width, like with SetAdvance(0). Options setting only other fields, which leave it zero,
+	// keep the default advance
 	Advance int
+
+	// The weight of the font the symbol was rendered with. Defaults to FontWeightRegular
+	Weight FontWeight
+
+	// Whether the font the symbol was rendered with is italic
+	Italic bool
+
+	// Whether the symbol belongs to a right-to-left script. See FontSymbol.SetRTL
+	RTL bool
+
+	// Where the glyph starts inside the image, when the image has some padding. See
+	// FontSymbol.SetOrigin
+	Origin image.Point
+
+	// Whether the image is drawn over a transparent background. Pixels less than half opaque are
+	// taken as background, whatever their color, and the rest as ink, ignoring their alpha. The
+	// background is made black for light ink and white for dark ink
+	Alpha bool
+
+	// Whether to use the alpha as intensity when the image is defined only by its alpha channel,
+	// all its pixels of the same color. See OCR.AlphaOnly
+	AlphaOnly bool
 }
 
 type fontSymbolLookup struct {
@@ -66,33 +276,56 @@ type fontSymbolLookup struct {
 	x, y int
 	g    float64
 	size int
+	// left is the left edge of the area the symbol was searched in, the indentation of its line is
+	// measured from
+	left int
+	// score is the similarity used when comparing overlapping lookups. Defaults to g
+	score float64
+	// alternatives are the lookups of the same symbol, from other font families, removed for
+	// overlapping this one. Only kept with SameLabelKeepAlternatives
+	alternatives []*fontSymbolLookup
+	// lowConfidence is set for the best guesses of RecognizeBestEffort, scoring below the threshold
+	lowConfidence bool
 }
 
 func newFontSymbolLookup(fs *FontSymbol, x, y int, g float64) *fontSymbolLookup {
-	return &fontSymbolLookup{fs, x, y, g, fs.image.size}
+	return &fontSymbolLookup{fs: fs, x: x, y: y, g: g, size: fs.image.size, score: g}
+}
+
+// bounds is the area taken by the glyph found, from its origin to its advance (or the end of its
+// image) and bottom
+func (l *fontSymbolLookup) bounds() image.Rectangle {
+	x, y := l.x+l.fs.originX, l.y+l.fs.originY
+	width := l.fs.width - l.fs.originX
+	if l.fs.advance != math.MaxInt {
+		width = l.fs.advance
+	}
+	return image.Rect(x, y, x+width, l.y+l.fs.height)
 }
 
 func (l *fontSymbolLookup) cross(f *fontSymbolLookup) bool {
-	r := image.Rect(l.x, l.y, l.x+l.fs.Advance(), l.y+l.fs.height)
-	r2 := image.Rect(f.x, f.y, f.x+f.fs.Advance(), f.y+f.fs.height)
+	r := l.bounds()
+	r2 := f.bounds()
 
 	return r.Intersect(r2) != image.Rectangle{}
 }
 
 func (l *fontSymbolLookup) yCross(f *fontSymbolLookup) bool {
-	ly2 := l.y + l.fs.height
-	fy2 := f.y + f.fs.height
+	ly1, ly2 := l.y+l.fs.originY, l.y+l.fs.height
+	fy1, fy2 := f.y+f.fs.originY, f.y+f.fs.height
 
-	return (f.y >= l.y && f.y <= ly2) || (fy2 >= l.y && fy2 <= ly2)
+	return (fy1 >= ly1 && fy1 <= ly2) || (fy2 >= ly1 && fy2 <= ly2)
 }
 
 func (l *fontSymbolLookup) biggerThan(other *fontSymbolLookup, maxSize2 int) bool {
-	if abs(abs(l.size)-abs(other.size)) >= maxSize2 {
+	// alternative images for the same symbol don't eat each other just because of their size
+	sameSymbol := l.fs.symbol == other.fs.symbol
+	if !sameSymbol && abs(abs(l.size)-abs(other.size)) >= maxSize2 {
 		return other.size < l.size
 	}
 
 	// better quality goes first
-	diff := l.g - other.g
+	diff := l.score - other.score
 	if diff != 0 {
 		return diff > 0
 	}
@@ -102,48 +335,179 @@ func (l *fontSymbolLookup) biggerThan(other *fontSymbolLookup, maxSize2 int) boo
 }
 
 func (l *fontSymbolLookup) comesAfter(f *fontSymbolLookup) bool {
+	lx, ly := l.x+l.fs.originX, l.y+l.fs.originY
+	fx, fy := f.x+f.fs.originX, f.y+f.fs.originY
+
 	r := 0
 	if !l.yCross(f) {
-		r = l.y - f.y
+		r = ly - fy
 	}
 
 	if r == 0 {
-		r = l.x - f.x
+		r = lx - fx
 	}
 
 	if r == 0 {
-		r = l.y - f.y
+		r = ly - fy
 	}
 
 	return r < 0
 }
 
+// precedes defines a total order between lookups, based on their position and symbol. It is
+// used to break ties in the other comparators
+func (l *fontSymbolLookup) precedes(f *fontSymbolLookup) bool {
+	if l.x != f.x {
+		return l.x < f.x
+	}
+	if l.y != f.y {
+		return l.y < f.y
+	}
+	if l.fs.symbol != f.fs.symbol {
+		return l.fs.symbol < f.fs.symbol
+	}
+	if l.size != f.size {
+		return l.size > f.size
+	}
+	if l.score != f.score {
+		return l.score > f.score
+	}
+	return l.fs.width < f.fs.width
+}
+
 func (l *fontSymbolLookup) String() string {
 	return fmt.Sprintf("'%s'(%d,%d,%d)[%f]", l.fs.symbol, l.x, l.y, l.size, l.g)
 }
 
+// LoadFontSymbols loads the symbols of a fontset from the given folder, like OCR.LoadFont does,
+// without adding them to any OCR or font family. Use it to inspect or transform the symbols, like
+// filtering them, before adding them with OCR.AddFontFamily or OCR.AddSymbols.
+func LoadFontSymbols(path string) ([]*FontSymbol, error) {
+	return loadFont(path)
+}
+
+// LoadFontSymbol loads a single symbol of a fontset, from the image file with the given name in
+// the folder in path. The symbol is named after the file, like the ones loaded by LoadFontSymbols.
+func LoadFontSymbol(path, fileName string) (*FontSymbol, error) {
+	return loadSymbol(os.DirFS(path), ".", fileName)
+}
+
 func loadFont(path string) ([]*FontSymbol, error) {
-	files, err := ioutil.ReadDir(path)
+	return loadFontFS(os.DirFS(path), ".")
+}
+
+func loadFontFS(fsys fs.FS, dir string) ([]*FontSymbol, error) {
+	files, err := symbolFiles(fsys, dir)
 	if err != nil {
 		return nil, err
 	}
 
-	fonts := make([]*FontSymbol, 0)
+	fonts := make([]*FontSymbol, 0, len(files))
 	for _, f := range files {
+		fs, err := loadSymbol(fsys, dir, f)
+		if err != nil {
+			return nil, err
+		}
+		fonts = append(fonts, fs)
+	}
+	return fonts, nil
+}
+
+// loadFontParallel works like loadFontFS, loading the symbols with the given number of workers
+// and calling progress (if not nil) after each one is loaded
+func loadFontParallel(fsys fs.FS, dir string, workers int, progress func(done, total int)) ([]*FontSymbol, error) {
+	files, err := symbolFiles(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fonts := make([]*FontSymbol, len(files))
+	errs := make([]error, len(files))
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range files {
+			next <- i
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	done := 0
+	for w := 0; w < max(workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fonts[i], errs[i] = loadSymbol(fsys, dir, files[i])
+				if progress != nil {
+					mu.Lock()
+					done++
+					progress(done, len(files))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fonts, nil
+}
+
+// symbolFiles lists the names of the files of the symbols of the font in dir, skipping folders
+// and hidden files
+func symbolFiles(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, f := range entries {
 		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
 			continue
 		}
-		fs, err := loadSymbol(path, f.Name())
+		files = append(files, f.Name())
+	}
+	return files, nil
+}
+
+func loadFontJSON(r io.Reader) ([]*FontSymbol, error) {
+	var entries map[string]string
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fonts := make([]*FontSymbol, 0, len(names))
+	for _, name := range names {
+		data, err := base64.StdEncoding.DecodeString(entries[name])
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid base64 image for symbol %q: %w", name, err)
 		}
-		fonts = append(fonts, fs)
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid image for symbol %q: %w", name, err)
+		}
+
+		symbolName := strings.Replace(name, "\u200b", "", -1) // Remove zero width spaces
+		fonts = append(fonts, NewFontSymbol(symbolName, img))
 	}
 	return fonts, nil
 }
 
-func loadSymbol(path string, fileName string) (*FontSymbol, error) {
-	imageFile, err := os.Open(path + "/" + fileName)
+func loadSymbol(fsys fs.FS, dir string, fileName string) (*FontSymbol, error) {
+	imageFile, err := fsys.Open(path.Join(dir, fileName))
 	if err != nil {
 		return nil, err
 	}
@@ -154,7 +518,7 @@ func loadSymbol(path string, fileName string) (*FontSymbol, error) {
 		return nil, err
 	}
 
-	nameWithoutExtension := strings.TrimSuffix(fileName, ".png")
+	nameWithoutExtension := strings.TrimSuffix(fileName, filepath.Ext(fileName))
 	symbolName, err := url.QueryUnescape(nameWithoutExtension)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,122 @@
+package lookup
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// bmChar is a glyph of a BMFont descriptor
+type bmChar struct {
+	id, x, y, width, height, advance int
+}
+
+// loadFontBM loads the symbols of a BMFont, described by the text descriptor (.fnt) in fntPath,
+// and cut from the texture atlas in texturePath. Returns the symbols and the face of the font
+func loadFontBM(fntPath, texturePath string) ([]*FontSymbol, string, error) {
+	fnt, err := os.Open(fntPath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer fnt.Close()
+
+	face, chars, err := parseBMFont(fnt)
+	if err != nil {
+		return nil, "", err
+	}
+	if face == "" {
+		face = strings.TrimSuffix(filepath.Base(fntPath), filepath.Ext(fntPath))
+	}
+
+	textureFile, err := os.Open(texturePath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer textureFile.Close()
+	texture, _, err := image.Decode(textureFile)
+	if err != nil {
+		return nil, "", err
+	}
+	atlas, ok := texture.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported texture image type %T", texture)
+	}
+
+	bounds := texture.Bounds()
+	symbols := make([]*FontSymbol, 0, len(chars))
+	for _, c := range chars {
+		if c.width == 0 || c.height == 0 {
+			// whitespace has no image to match
+			continue
+		}
+		r := image.Rect(c.x, c.y, c.x+c.width, c.y+c.height).Add(bounds.Min)
+		if !r.In(bounds) {
+			return nil, "", fmt.Errorf("glyph %q at %v is outside the texture %v", rune(c.id), r, bounds)
+		}
+		symbols = append(symbols, NewFontSymbolOpts(string(rune(c.id)), atlas.SubImage(r), &NewFontSymbolOptions{Advance: c.advance}))
+	}
+	return symbols, face, nil
+}
+
+// parseBMFont parses a BMFont descriptor in text format, returning the face of the font and its
+// glyphs. Only the single texture (page) case is supported
+func parseBMFont(r io.Reader) (string, []bmChar, error) {
+	var face string
+	var chars []bmChar
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		tag, attrs := parseBMFontLine(scanner.Text())
+		switch tag {
+		case "info":
+			face = attrs["face"]
+		case "page":
+			if attrs["id"] != "0" {
+				return "", nil, fmt.Errorf("line %d: only fonts with a single page are supported", line)
+			}
+		case "char":
+			var c bmChar
+			fields := []struct {
+				key   string
+				value *int
+			}{{"id", &c.id}, {"x", &c.x}, {"y", &c.y}, {"width", &c.width}, {"height", &c.height}, {"xadvance", &c.advance}}
+			for _, f := range fields {
+				v, err := strconv.Atoi(attrs[f.key])
+				if err != nil {
+					return "", nil, fmt.Errorf("line %d: invalid %s: %w", line, f.key, err)
+				}
+				*f.value = v
+			}
+			chars = append(chars, c)
+		}
+	}
+	return face, chars, scanner.Err()
+}
+
+// parseBMFontLine splits a line of a BMFont descriptor in its tag and its key=value attributes.
+// Values may be quoted, to include spaces
+func parseBMFontLine(line string) (string, map[string]string) {
+	line = strings.TrimSpace(line)
+	tag, rest, _ := strings.Cut(line, " ")
+	attrs := make(map[string]string)
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		if strings.HasPrefix(value, `"`) {
+			value, rest, _ = strings.Cut(value[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(value, " ")
+		}
+		attrs[strings.TrimSpace(key)] = value
+	}
+	return tag, attrs
+}
@@ -0,0 +1,70 @@
+package lookup
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// writeBMFont writes the symbols of a font folder as a BMFont, with all images side by side
+// in the texture. Returns the paths of the descriptor and the texture
+func writeBMFont(dir string, fontPath string, face string) (string, string) {
+	symbols, _ := loadFont(fontPath)
+	width, height := 0, 0
+	for _, s := range symbols {
+		width += s.width
+		height = max(height, s.height)
+	}
+
+	texture := image.NewGray(image.Rect(0, 0, width, height))
+	fnt := fmt.Sprintf("info face=\"%s\" size=16\ncommon lineHeight=%d base=%d\npage id=0 file=\"font.png\"\nchars count=%d\n",
+		face, height, height, len(symbols))
+	x := 0
+	for _, s := range symbols {
+		draw.Draw(texture, image.Rect(x, 0, x+s.width, s.height), s.image.gray(), image.Point{}, draw.Src)
+		fnt += fmt.Sprintf("char id=%d x=%d y=0 width=%d height=%d xoffset=0 yoffset=0 xadvance=%d page=0 chnl=15\n",
+			[]rune(s.symbol)[0], x, s.width, s.height, s.Advance())
+		x += s.width
+	}
+	fnt += "char id=32 x=0 y=0 width=0 height=0 xoffset=0 yoffset=0 xadvance=4 page=0 chnl=15\n"
+
+	fntPath, texturePath := filepath.Join(dir, "font.fnt"), filepath.Join(dir, "font.png")
+	_ = os.WriteFile(fntPath, []byte(fnt), 0o644)
+	f, _ := os.Create(texturePath)
+	defer f.Close()
+	_ = png.Encode(f, texture)
+	return fntPath, texturePath
+}
+
+func TestOCRLoadFontBM(t *testing.T) {
+	Convey("Given a BMFont with the symbols of a font", t, func() {
+		fntPath, texturePath := writeBMFont(t.TempDir(), "testdata/font_1", "Font One")
+		ocr := NewOCR(0.8)
+
+		Convey("It loads the symbols in a family named after the face", func() {
+			So(ocr.LoadFontBM(fntPath, texturePath), ShouldBeNil)
+			So(ocr.fontFamilies["Font One"], ShouldHaveLength, 13)
+
+			text, err := ocr.Recognize(loadImageColor("testdata/test3.png"))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It fails when a glyph is outside the texture", func() {
+			data, _ := os.ReadFile(fntPath)
+			_ = os.WriteFile(fntPath, []byte(strings.Replace(string(data), "char id=48 x=", "char id=48 x=1000", 1)), 0o644)
+			So(ocr.LoadFontBM(fntPath, texturePath), ShouldNotBeNil)
+		})
+
+		Convey("It fails when the texture does not exist", func() {
+			So(ocr.LoadFontBM(fntPath, "testdata/missing.png"), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,121 @@
+package lookup
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"io"
+)
+
+// fontPackVersion is the version of the format written by SaveFontPack. Version 1 stored the image
+// of each symbol with it, and version 2 stores each different image once, in Images
+const fontPackVersion = 2
+
+// fontPack is the content of a font pack, as encoded by SaveFontPack
+type fontPack struct {
+	Version int
+	Symbols []fontPackSymbol
+	Images  []fontPackImage
+}
+
+// fontPackImage is a gray scale image stored in a font pack
+type fontPackImage struct {
+	Width  int
+	Height int
+	Pix    []byte
+}
+
+// fontPackSymbol is a FontSymbol, as stored in a font pack
+type fontPackSymbol struct {
+	Symbol string
+	Family string
+	// Image is the index of the image of the symbol in Images. Since version 2
+	Image int
+	// Width, Height and Pix are the image of the symbol. Only in version 1
+	Width    int
+	Height   int
+	Pix      []byte
+	Advance  int
+	Weight   FontWeight
+	Italic   bool
+	MinScore float64
+	Origin   image.Point
+	RTL      bool
+}
+
+// SaveFontPack writes the symbols, with their images and attributes, to a single binary font pack
+// that can be read back with LoadFontPack. Loading a pack is much faster than loading a fontset
+// from a folder, as there is only one file to read and no images to decode.
+func SaveFontPack(w io.Writer, symbols []*FontSymbol) error {
+	pack := fontPack{Version: fontPackVersion, Symbols: make([]fontPackSymbol, len(symbols))}
+	images := make(map[uint64][]int)
+	for i, s := range symbols {
+		img := fontPackImage{Width: s.width, Height: s.height, Pix: s.image.gray().Pix}
+		h := fnv.New64a()
+		_, _ = h.Write(img.Pix)
+		key := h.Sum64() ^ uint64(img.Width)
+		index := -1
+		for _, j := range images[key] {
+			if pack.Images[j].Width == img.Width && bytes.Equal(pack.Images[j].Pix, img.Pix) {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			index = len(pack.Images)
+			pack.Images = append(pack.Images, img)
+			images[key] = append(images[key], index)
+		}
+
+		pack.Symbols[i] = fontPackSymbol{
+			Symbol:   s.symbol,
+			Family:   s.family,
+			Image:    index,
+			Advance:  s.advance,
+			Weight:   s.weight,
+			Italic:   s.italic,
+			MinScore: s.minScore,
+			Origin:   s.Origin(),
+			RTL:      s.rtl,
+		}
+	}
+	return gob.NewEncoder(w).Encode(pack)
+}
+
+// LoadFontPack reads the symbols of a font pack written by SaveFontPack. The symbols keep the
+// name of the font family they belonged to, but are not added to any OCR: use AddFontFamily or
+// AddSymbols for that.
+func LoadFontPack(r io.Reader) ([]*FontSymbol, error) {
+	var pack fontPack
+	if err := gob.NewDecoder(r).Decode(&pack); err != nil {
+		return nil, err
+	}
+	if pack.Version < 1 || pack.Version > fontPackVersion {
+		return nil, fmt.Errorf("unsupported font pack version %d", pack.Version)
+	}
+
+	symbols := make([]*FontSymbol, len(pack.Symbols))
+	for i, p := range pack.Symbols {
+		stored := fontPackImage{Width: p.Width, Height: p.Height, Pix: p.Pix}
+		if pack.Version > 1 {
+			if p.Image < 0 || p.Image >= len(pack.Images) {
+				return nil, fmt.Errorf("invalid image %d for symbol %q", p.Image, p.Symbol)
+			}
+			stored = pack.Images[p.Image]
+		}
+		if stored.Width <= 0 || stored.Height <= 0 || len(stored.Pix) != stored.Width*stored.Height {
+			return nil, fmt.Errorf("invalid image of %dx%d pixels for symbol %q", stored.Width, stored.Height, p.Symbol)
+		}
+		if p.Advance < 0 {
+			return nil, fmt.Errorf("invalid advance %d for symbol %q", p.Advance, p.Symbol)
+		}
+		img := &image.Gray{Pix: stored.Pix, Stride: stored.Width, Rect: image.Rect(0, 0, stored.Width, stored.Height)}
+		fs := NewFontSymbolOpts(p.Symbol, img, &NewFontSymbolOptions{Advance: p.Advance, Weight: p.Weight, Italic: p.Italic, Origin: p.Origin, RTL: p.RTL})
+		fs.family = p.Family
+		fs.minScore = p.MinScore
+		symbols[i] = fs
+	}
+	return symbols, nil
+}
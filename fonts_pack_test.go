@@ -0,0 +1,109 @@
+package lookup
+
+import (
+	"bytes"
+	"encoding/gob"
+	_ "image/png"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFontPack(t *testing.T) {
+	Convey("Given a loaded fontset", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		ocr.allSymbols[3].SetAdvance(12)
+		ocr.allSymbols[4].SetMinScore(0.9)
+		ocr.allSymbols[5].SetRTL(true)
+
+		Convey("When I save it to a font pack and load it back", func() {
+			var buf bytes.Buffer
+			So(SaveFontPack(&buf, ocr.allSymbols), ShouldBeNil)
+			symbols, err := LoadFontPack(&buf)
+			So(err, ShouldBeNil)
+
+			Convey("It restores all symbols with their attributes", func() {
+				So(symbols, ShouldHaveLength, len(ocr.allSymbols))
+				for i, s := range symbols {
+					original := ocr.allSymbols[i]
+					So(s.symbol, ShouldEqual, original.symbol)
+					So(s.Family(), ShouldEqual, "font_1")
+					So(s.Advance(), ShouldEqual, original.Advance())
+					So(s.MinScore(), ShouldEqual, original.MinScore())
+					So(s.RTL(), ShouldEqual, original.RTL())
+					So(s.image.gray().Pix, ShouldResemble, original.image.gray().Pix)
+				}
+			})
+
+			Convey("It recognizes text like the original fontset", func() {
+				loaded := NewOCR(0.8)
+				loaded.AddFontFamily("font_1", symbols...)
+				text, _ := loaded.Recognize(loadImageColor("testdata/test3.png"))
+				expected, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+				So(text, ShouldEqual, expected)
+			})
+		})
+
+		Convey("When the font pack has an unknown version", func() {
+			var buf bytes.Buffer
+			_ = gob.NewEncoder(&buf).Encode(fontPack{Version: fontPackVersion + 1})
+			_, err := LoadFontPack(&buf)
+
+			Convey("It returns an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When a symbol of the font pack has an invalid image", func() {
+			var buf bytes.Buffer
+			_ = gob.NewEncoder(&buf).Encode(fontPack{Version: 1, Symbols: []fontPackSymbol{{Symbol: "x", Width: 2, Height: 2, Pix: []byte{1}}}})
+			_, err := LoadFontPack(&buf)
+
+			Convey("It returns an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When a symbol of the font pack refers to a missing image", func() {
+			var buf bytes.Buffer
+			_ = gob.NewEncoder(&buf).Encode(fontPack{Version: fontPackVersion, Symbols: []fontPackSymbol{{Symbol: "x", Image: 1}}})
+			_, err := LoadFontPack(&buf)
+
+			Convey("It returns an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When I save symbols sharing the same image", func() {
+			symbols := append(append([]*FontSymbol{}, ocr.allSymbols...), ocr.allSymbols...)
+			var buf bytes.Buffer
+			So(SaveFontPack(&buf, symbols), ShouldBeNil)
+
+			Convey("It stores each image once", func() {
+				var pack fontPack
+				So(gob.NewDecoder(bytes.NewReader(buf.Bytes())).Decode(&pack), ShouldBeNil)
+				So(pack.Images, ShouldHaveLength, len(ocr.allSymbols))
+				loaded, err := LoadFontPack(&buf)
+				So(err, ShouldBeNil)
+				So(loaded, ShouldHaveLength, len(symbols))
+				So(loaded[len(ocr.allSymbols)].image.gray().Pix, ShouldResemble, ocr.allSymbols[0].image.gray().Pix)
+			})
+		})
+
+		Convey("When I load a font pack of the first version", func() {
+			original := ocr.allSymbols[5]
+			pix := original.image.gray().Pix
+			var buf bytes.Buffer
+			_ = gob.NewEncoder(&buf).Encode(fontPack{Version: 1, Symbols: []fontPackSymbol{{Symbol: original.symbol, Width: original.width, Height: original.height, Pix: pix}}})
+			symbols, err := LoadFontPack(&buf)
+
+			Convey("It restores the symbols with their images", func() {
+				So(err, ShouldBeNil)
+				So(symbols, ShouldHaveLength, 1)
+				So(symbols[0].symbol, ShouldEqual, original.symbol)
+				So(symbols[0].image.gray().Pix, ShouldResemble, pix)
+			})
+		})
+	})
+}
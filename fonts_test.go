@@ -1,7 +1,13 @@
 package lookup
 
 import (
+	"image"
+	"image/color"
+	"image/jpeg"
 	_ "image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -22,6 +28,48 @@ func TestFontSymbol(t *testing.T) {
 	})
 }
 
+func TestFontSymbolAlphaOnly(t *testing.T) {
+	Convey("Given a glyph defined only in the alpha channel", t, func() {
+		glyph := alphaOnly(loadImageGray("testdata/font_1/3.png").(*image.Gray))
+
+		Convey("When I create a fontSymbol from it", func() {
+			fs := NewFontSymbol("3", glyph)
+
+			Convey("It ignores the alpha by default, leaving it blank", func() {
+				So(fs.image.channels[0].dev2n(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I create a fontSymbol from it with AlphaOnly", func() {
+			fs := NewFontSymbolOpts("3", glyph, &NewFontSymbolOptions{AlphaOnly: true})
+
+			Convey("It uses the alpha as intensity", func() {
+				So(fs.image.channels[0].dev2n(), ShouldBeGreaterThan, 0)
+				So(fs.image.gray().Pix, ShouldResemble, loadImageGray("testdata/font_1/3.png").(*image.Gray).Pix)
+			})
+
+			Convey("It is recognized in an alpha only image by an OCR with AlphaOnly", func() {
+				ocr := NewOCR(0.8)
+				ocr.AlphaOnly = true
+				ocr.AddSymbols(fs)
+				img := alphaOnly(loadImageGray("testdata/test3.png").(*image.Gray))
+				text, err := ocr.Recognize(img)
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3\n3")
+			})
+		})
+	})
+}
+
+// alphaOnly creates a black image with the gray image intensities as alpha
+func alphaOnly(gray *image.Gray) *image.NRGBA {
+	img := image.NewNRGBA(gray.Bounds())
+	for i, v := range gray.Pix {
+		img.Pix[i*4+3] = v
+	}
+	return img
+}
+
 func TestLoadFont(t *testing.T) {
 	Convey("Given a font directory", t, func() {
 		Convey("When loading the symbols", func() {
@@ -43,3 +91,151 @@ func TestLoadFont(t *testing.T) {
 		})
 	})
 }
+
+func TestLoadFontSymbols(t *testing.T) {
+	Convey("When I load the symbols of a fontset", t, func() {
+		symbols, err := LoadFontSymbols("testdata/font_1")
+
+		Convey("It loads all symbols, without a family", func() {
+			So(err, ShouldBeNil)
+			So(symbols, ShouldHaveLength, 13)
+			for _, s := range symbols {
+				So(s.Family(), ShouldBeEmpty)
+			}
+		})
+
+		Convey("They can be filtered before adding them to an OCR", func() {
+			ocr := NewOCR(0.8)
+			for _, s := range symbols {
+				if s.String() != "€" {
+					ocr.AddSymbols(s)
+				}
+			}
+			text, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+			So(text, ShouldEqual, "3662\n3 2 /")
+		})
+	})
+
+	Convey("When I load a single symbol of a fontset", t, func() {
+		Convey("It names it after its file", func() {
+			s, err := LoadFontSymbol("testdata/font_1", "%2f.png")
+			So(err, ShouldBeNil)
+			So(s.String(), ShouldEqual, "/")
+			So(s.image.gray().Pix, ShouldResemble, loadImageGray("testdata/font_1/%2f.png").(*image.Gray).Pix)
+		})
+
+		Convey("It fails for a missing file", func() {
+			_, err := LoadFontSymbol("testdata/font_1", "missing.png")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestLoadFontJPEG(t *testing.T) {
+	Convey("Given a font directory with JPEG images", t, func() {
+		dir := t.TempDir()
+		for name, file := range map[string]string{"3.jpg": "3.png", "%2F.jpeg": "%2f.png"} {
+			f, _ := os.Create(filepath.Join(dir, name))
+			_ = jpeg.Encode(f, loadImageGray("testdata/font_1/"+file), &jpeg.Options{Quality: 100})
+			_ = f.Close()
+		}
+
+		Convey("When loading the symbols", func() {
+			fonts, err := loadFont(dir)
+
+			Convey("It names the symbols without the extension", func() {
+				So(err, ShouldBeNil)
+				var names []string
+				for _, f := range fonts {
+					names = append(names, f.symbol)
+				}
+				So(names, ShouldResemble, []string{"/", "3"})
+			})
+		})
+	})
+}
+
+func TestNewFontSymbolAlpha(t *testing.T) {
+	Convey("Given a white glyph over a transparent background of random colors", t, func() {
+		gray := loadImageGray("testdata/font_1/3.png").(*image.Gray)
+		glyph := image.NewNRGBA(gray.Bounds())
+		rnd := rand.New(rand.NewSource(1))
+		const background = 46
+		for i, v := range gray.Pix {
+			p := glyph.Pix[i*4 : i*4+4]
+			if v > background {
+				// antialiased edges are partially transparent
+				p[0], p[1], p[2], p[3] = 255, 255, 255, uint8((int(v)-background)*255/(255-background))
+			} else {
+				p[0], p[1], p[2], p[3] = uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), 0
+			}
+		}
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("A symbol ignoring the alpha does not match the text", func() {
+			g, _ := ScoreAt(img, NewFontSymbol("3", glyph), 6, 4)
+			So(g, ShouldBeLessThan, 0.8)
+		})
+
+		Convey("A symbol using the alpha matches the text", func() {
+			fs := NewFontSymbolAlpha("3", glyph)
+			g, _ := ScoreAt(img, fs, 6, 4)
+			So(g, ShouldBeGreaterThan, 0.9)
+		})
+
+		Convey("The transparent pixels are background", func() {
+			fs := NewFontSymbolAlpha("3", glyph)
+			So(fs.image.gray().GrayAt(0, 0).Y, ShouldEqual, 0)
+		})
+	})
+
+	Convey("Given a dark glyph over a transparent background", t, func() {
+		glyph := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+		glyph.SetNRGBA(1, 1, color.NRGBA{A: 255})
+
+		Convey("The background is white", func() {
+			fs := NewFontSymbolAlpha("x", glyph)
+			So(fs.image.gray().GrayAt(0, 0).Y, ShouldEqual, 255)
+			So(fs.image.gray().GrayAt(1, 1).Y, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestFontSymbolOrigin(t *testing.T) {
+	Convey("Given a symbol with padding at its left and top", t, func() {
+		padded := NewFontSymbolOpts("a", image.NewGray(image.Rect(0, 0, 14, 12)), &NewFontSymbolOptions{Origin: image.Pt(6, 2)})
+		glyph := NewFontSymbol("b", image.NewGray(image.Rect(0, 0, 8, 10)))
+
+		Convey("It keeps the origin", func() {
+			So(padded.Origin(), ShouldResemble, image.Pt(6, 2))
+			padded.SetOrigin(4, 0)
+			So(padded.Origin(), ShouldResemble, image.Pt(4, 0))
+		})
+
+		Convey("And a glyph found in its padding", func() {
+			a := newFontSymbolLookup(padded, 5, 0, 1)
+			b := newFontSymbolLookup(glyph, 0, 2, 1)
+
+			Convey("They don't cross, as the padding is not part of the glyph", func() {
+				So(a.cross(b), ShouldBeFalse)
+				So(b.cross(a), ShouldBeFalse)
+			})
+
+			Convey("The glyph is sorted before the padded symbol", func() {
+				So(b.comesAfter(a), ShouldBeTrue)
+				So(a.comesAfter(b), ShouldBeFalse)
+			})
+
+			Convey("They cross without the origin", func() {
+				padded.SetOrigin(0, 0)
+				So(a.cross(b), ShouldBeTrue)
+				So(a.comesAfter(b), ShouldBeFalse)
+			})
+
+			Convey("The advance is measured from the origin", func() {
+				glyph.SetAdvance(12)
+				So(a.cross(b), ShouldBeTrue)
+			})
+		})
+	})
+}
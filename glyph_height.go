@@ -0,0 +1,89 @@
+package lookup
+
+import (
+	"errors"
+	"image"
+	"sort"
+)
+
+// minGlyphPixels is the minimum number of ink pixels of a connected component for it to be
+// taken into account as a glyph, and not as noise
+const minGlyphPixels = 4
+
+// EstimateGlyphHeight estimates the height (in pixels) of the text in the image, without any
+// font loaded. Ink pixels, the ones closer to the color farthest from the most common
+// (background) color than to the background itself, are grouped in connected components, and
+// the median of their heights is returned. Use it to pick or scale the fontset that matches the
+// text in the image.
+func EstimateGlyphHeight(img image.Image) (int, error) {
+	var heights []int
+	for _, c := range inkComponents(ensureGrayScale(img).(*image.Gray)) {
+		heights = append(heights, c.Dy())
+	}
+
+	if len(heights) == 0 {
+		return 0, errors.New("no glyphs found in the image")
+	}
+	sort.Ints(heights)
+	return heights[len(heights)/2], nil
+}
+
+// inkComponents returns the bounds of the connected components of ink pixels of the image (at
+// the origin) with at least minGlyphPixels pixels. Ink pixels are the ones closer to the color
+// farthest from the most common (background) color than to the background itself
+func inkComponents(gray *image.Gray) []image.Rectangle {
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+
+	var histogram [256]int
+	for _, v := range gray.Pix {
+		histogram[v]++
+	}
+	background := 0
+	for v, n := range histogram {
+		if n > histogram[background] {
+			background = v
+		}
+	}
+	// ink is anything closer to the color farthest from the background than to the background
+	contrast := 0
+	for v, n := range histogram {
+		if n > 0 {
+			contrast = max(contrast, abs(v-background))
+		}
+	}
+	ink := func(x, y int) bool {
+		return abs(int(gray.Pix[y*gray.Stride+x])-background)*2 > contrast
+	}
+
+	var components []image.Rectangle
+	visited := make([]bool, w*h)
+	var stack []image.Point
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if visited[y*w+x] || !ink(x, y) {
+				continue
+			}
+			visited[y*w+x] = true
+			stack = append(stack[:0], image.Pt(x, y))
+			bounds, pixels := image.Rect(x, y, x+1, y+1), 0
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				pixels++
+				bounds = bounds.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+				for ny := max(p.Y-1, 0); ny <= min(p.Y+1, h-1); ny++ {
+					for nx := max(p.X-1, 0); nx <= min(p.X+1, w-1); nx++ {
+						if !visited[ny*w+nx] && ink(nx, ny) {
+							visited[ny*w+nx] = true
+							stack = append(stack, image.Pt(nx, ny))
+						}
+					}
+				}
+			}
+			if pixels >= minGlyphPixels {
+				components = append(components, bounds)
+			}
+		}
+	}
+	return components
+}
@@ -0,0 +1,35 @@
+package lookup
+
+import (
+	"image"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEstimateGlyphHeight(t *testing.T) {
+	Convey("Given an image with text", t, func() {
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It estimates the height of the glyphs", func() {
+			height, err := EstimateGlyphHeight(img)
+			So(err, ShouldBeNil)
+			So(height, ShouldEqual, 14)
+		})
+
+		Convey("It estimates the height of scaled glyphs", func() {
+			height, err := EstimateGlyphHeight(scale(ensureGrayScale(img).(*image.Gray), 2, 2))
+			So(err, ShouldBeNil)
+			So(height, ShouldEqual, 28)
+		})
+	})
+
+	Convey("Given an image without text", t, func() {
+		img := newGrayImage(10, 10, nil)
+
+		Convey("It fails to estimate the height", func() {
+			_, err := EstimateGlyphHeight(img)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
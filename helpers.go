@@ -5,11 +5,25 @@ import (
 	"image/color"
 )
 
+// grayAtOrigin returns the image with its bounds starting at (0, 0) and no padding between rows,
+// copying the pixels only if needed, as the recognition expects images laid out this way
+func grayAtOrigin(img *image.Gray) *image.Gray {
+	b := img.Bounds()
+	if b.Min == (image.Point{}) && img.Stride == b.Dx() {
+		return img
+	}
+	moved := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		copy(moved.Pix[y*moved.Stride:(y+1)*moved.Stride], img.Pix[img.PixOffset(b.Min.X, b.Min.Y+y):])
+	}
+	return moved
+}
+
 // ensureGrayScale is a helper function to convert any image.Image to image.Gray, using a simple
 // average of the color channels. Ignores luminosity.
 func ensureGrayScale(imgSrc image.Image) image.Image {
-	if _, ok := imgSrc.(*image.Gray); ok && (imgSrc.Bounds().Min == image.Point{}) {
-		return imgSrc
+	if gray, ok := imgSrc.(*image.Gray); ok {
+		return grayAtOrigin(gray)
 	}
 	min := imgSrc.Bounds().Min
 	max := imgSrc.Bounds().Max
@@ -30,6 +44,81 @@ func ensureGrayScale(imgSrc image.Image) image.Image {
 	return grayImage
 }
 
+// grayScale converts any image.Image to image.Gray like ensureGrayScale, but with alphaOnly the
+// images whose pixels all have the same color, only varying in alpha, use the alpha as intensity
+// instead. Telling them apart reads every pixel of an image that is neither gray nor opaque (up
+// to the first one of another color) on top of the conversion, so it is only done when asked for
+func grayScale(img image.Image, alphaOnly bool) image.Image {
+	if alphaOnly {
+		if alpha, ok := alphaOnlyToGray(img); ok {
+			return alpha
+		}
+	}
+	return ensureGrayScale(img)
+}
+
+// alphaOnlyToGray converts an image whose pixels all have the same color, only varying in alpha,
+// to a gray scale image using the alpha as intensity. Returns false for any other image.
+func alphaOnlyToGray(imgSrc image.Image) (*image.Gray, bool) {
+	if o, ok := imgSrc.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return nil, false
+	}
+	b := imgSrc.Bounds()
+	if b.Empty() {
+		return nil, false
+	}
+	first := color.NRGBAModel.Convert(imgSrc.At(b.Min.X, b.Min.Y)).(color.NRGBA)
+	alphaVaries := false
+	grayImage := image.NewGray(image.Rectangle{Max: b.Size()})
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p := color.NRGBAModel.Convert(imgSrc.At(x, y)).(color.NRGBA)
+			if p.R != first.R || p.G != first.G || p.B != first.B {
+				return nil, false
+			}
+			alphaVaries = alphaVaries || p.A != first.A
+			grayImage.SetGray(x-b.Min.X, y-b.Min.Y, color.Gray{Y: p.A})
+		}
+	}
+	return grayImage, alphaVaries
+}
+
+// alphaToGray converts an image drawn over a transparent background to gray scale. Pixels at least
+// half opaque are ink, converted like ensureGrayScale does (ignoring their alpha), and the rest
+// are background, whatever their color. The background is black for light ink and white for dark
+// ink, so the ink always stands out.
+func alphaToGray(imgSrc image.Image) *image.Gray {
+	b := imgSrc.Bounds()
+	grayImage := image.NewGray(image.Rectangle{Max: b.Size()})
+	ink := make([]bool, len(grayImage.Pix))
+	sum, inked := 0, 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p := color.NRGBAModel.Convert(imgSrc.At(x, y)).(color.NRGBA)
+			if p.A < 128 {
+				continue
+			}
+			i := grayImage.PixOffset(x-b.Min.X, y-b.Min.Y)
+			p.A = 255
+			grayImage.Pix[i] = nrgbaToGray(p).Y
+			ink[i] = true
+			sum += int(grayImage.Pix[i])
+			inked++
+		}
+	}
+
+	var background uint8
+	if inked > 0 && sum/inked < 128 {
+		background = 255
+	}
+	for i := range grayImage.Pix {
+		if !ink[i] {
+			grayImage.Pix[i] = background
+		}
+	}
+	return grayImage
+}
+
 func nrgbaToGray(pixel color.Color) color.Gray {
 	p := pixel.(color.NRGBA)
 	m := (float64(p.R) + float64(p.G) + float64(p.B)) / 3
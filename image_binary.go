@@ -3,6 +3,7 @@ package lookup
 import (
 	"image"
 	"image/color"
+	"math"
 )
 
 type channelType int
@@ -37,6 +38,12 @@ func (c *imageBinaryChannel) dev2n() float64 {
 	return c.integralImage.dev2nRect(0, 0, c.width-1, c.height-1)
 }
 
+// Standard deviation of the pixels inside the rect
+func (c *imageBinaryChannel) stdDevRect(x1, y1, x2, y2 int) float64 {
+	size := float64((x2 - x1 + 1) * (y2 - y1 + 1))
+	return math.Sqrt(math.Max(c.dev2nRect(x1, y1, x2, y2), 0) / size)
+}
+
 // Container for ImageBinaryChannels (one for each channel)
 // It auto-detects if the image is RGB or GrayScale
 type imageBinary struct {
@@ -44,6 +51,70 @@ type imageBinary struct {
 	width    int
 	height   int
 	size     int
+	// offset is the position of the top-left corner in the original image coordinates
+	offset image.Point
+}
+
+// inkDeviation is the minimum standard deviation of the pixels of an area for it to be
+// considered as containing ink, and not only background (and some noise)
+const inkDeviation = 24
+
+// hasInk checks if the rect contains anything other than a uniform background, in any channel
+func (ib *imageBinary) hasInk(x1, y1, x2, y2 int) bool {
+	x1, y1 = max(x1, 0), max(y1, 0)
+	x2, y2 = min(x2, ib.width-1), min(y2, ib.height-1)
+	if x1 > x2 || y1 > y2 {
+		return false
+	}
+	for _, c := range ib.channels {
+		if c.stdDevRect(x1, y1, x2, y2) >= inkDeviation {
+			return true
+		}
+	}
+	return false
+}
+
+// inkBounds returns the bounding box of the pixels inside the rect that differ from the
+// background, taken as the most common value of each channel. Returns an empty rectangle if
+// there is no ink
+func (ib *imageBinary) inkBounds(x1, y1, x2, y2 int) image.Rectangle {
+	var bounds image.Rectangle
+	for _, c := range ib.channels {
+		var histogram [256]int
+		value := func(x, y int) float64 {
+			return c.zeroMeanImage[y*c.width+x] + c.integralImage.mean
+		}
+		for y := y1; y <= y2; y++ {
+			for x := x1; x <= x2; x++ {
+				histogram[uint8(math.Max(0, math.Min(255, math.Round(value(x, y)))))]++
+			}
+		}
+		background := 0
+		for v, n := range histogram {
+			if n > histogram[background] {
+				background = v
+			}
+		}
+		for y := y1; y <= y2; y++ {
+			for x := x1; x <= x2; x++ {
+				if math.Abs(value(x, y)-float64(background)) >= inkDeviation {
+					bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+	}
+	return bounds
+}
+
+// gray rebuilds the gray scale image this imageBinary was created from. Only the first channel is
+// used, so this is only meaningful for gray scale imageBinaries
+func (ib *imageBinary) gray() *image.Gray {
+	c := ib.channels[0]
+	img := image.NewGray(image.Rect(0, 0, ib.width, ib.height))
+	for i, v := range c.zeroMeanImage {
+		img.Pix[i] = uint8(math.Max(0, math.Min(255, math.Round(v+c.integralImage.mean))))
+	}
+	return img
 }
 
 func newImageBinary(img image.Image) *imageBinary {
@@ -1,6 +1,10 @@
 package lookup
 
-import "image"
+import (
+	"fmt"
+	"image"
+	"math"
+)
 
 // Lookup implements a image search algorithm based on Normalized Cross Correlation.
 // For an overview of the algorithm, see http://www.fmwconcepts.com/imagemagick/similar/index.php
@@ -42,3 +46,23 @@ func (l *Lookup) FindAllInRect(template image.Image, rect image.Rectangle, thres
 func (l *Lookup) FindAll(template image.Image, threshold float64) ([]GPoint, error) {
 	return l.FindAllInRect(template, image.Rect(0, 0, l.imgBin.width-1, l.imgBin.height-1), threshold)
 }
+
+// ScoreAt calculates how well the symbol matches the image with its top-left corner at the given
+// position, using the same score the OCR uses to find symbols (ranging from -1 to 1). This allows
+// implementing custom search strategies. The image is converted to gray scale.
+func ScoreAt(img image.Image, fs *FontSymbol, x, y int) (float64, error) {
+	bi := newImageBinary(ensureGrayScale(img))
+	offset := img.Bounds().Min
+	return scoreAt(bi, fs.image, x-offset.X, y-offset.Y)
+}
+
+func scoreAt(img *imageBinary, template *imageBinary, x, y int) (float64, error) {
+	if x < 0 || y < 0 || x+template.width > img.width || y+template.height > img.height {
+		return 0, fmt.Errorf("template of size %dx%d does not fit in the image at (%d, %d)", template.width, template.height, x, y)
+	}
+	g, err := lookup(img, template, x, y, -math.MaxFloat64)
+	if err != nil {
+		return 0, err
+	}
+	return g.G, nil
+}
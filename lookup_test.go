@@ -64,3 +64,27 @@ func TestLookup(t *testing.T) {
 
 	})
 }
+
+func TestScoreAt(t *testing.T) {
+	Convey("Given an image and a symbol", t, func() {
+		img := loadImageColor("testdata/test3.png")
+		fs := NewFontSymbol("/", loadImageColor("testdata/font_1/%2f.png"))
+
+		Convey("It scores a perfect match where the symbol is", func() {
+			g, err := ScoreAt(img, fs, 60, 27)
+			So(err, ShouldBeNil)
+			So(g, ShouldAlmostEqual, 1.0, 0.000001)
+		})
+
+		Convey("It scores a worse match anywhere else", func() {
+			g, err := ScoreAt(img, fs, 12, 27)
+			So(err, ShouldBeNil)
+			So(g, ShouldBeLessThan, 0.7)
+		})
+
+		Convey("It returns an error if the symbol does not fit in the image", func() {
+			_, err := ScoreAt(img, fs, 80, 27)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
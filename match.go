@@ -0,0 +1,79 @@
+package lookup
+
+import "image"
+
+// Match is a symbol recognized by the OCR, with the area of the image it was found in.
+type Match struct {
+	// The symbol recognized
+	Symbol string
+	// The area occupied by the symbol, in the coordinates of the image passed to the OCR
+	Rect image.Rectangle
+	// The similarity score of the match, ranging from -1 to 1
+	G float64
+	// The font family of the FontSymbol that matched. Empty for symbols not added to a family
+	Family string
+	// The weight of the FontSymbol that matched
+	Weight FontWeight
+	// Whether the FontSymbol that matched is italic
+	Italic bool
+	// Whether the symbol was found mirrored (horizontally flipped) in the image
+	Mirrored bool
+	// Whether the symbol scored below the threshold, and was only kept by RecognizeBestEffort as
+	// the best guess for some ink no other symbol covered
+	LowConfidence bool
+	// The matches of the same symbol, from other font families, found overlapping this one.
+	// Only filled when using SameLabelKeepAlternatives
+	Alternatives []Match
+}
+
+func (l *fontSymbolLookup) match(offset image.Point) Match {
+	m := Match{
+		Symbol:        l.fs.symbol,
+		Rect:          image.Rect(l.x, l.y, l.x+l.fs.width, l.y+l.fs.height).Add(offset),
+		G:             l.g,
+		Family:        l.fs.family,
+		Weight:        l.fs.weight,
+		Italic:        l.fs.italic,
+		Mirrored:      l.fs.mirrored,
+		LowConfidence: l.lowConfidence,
+	}
+	if len(l.alternatives) > 0 {
+		m.Alternatives = toMatches(l.alternatives, offset)
+	}
+	return m
+}
+
+func toMatches(all []*fontSymbolLookup, offset image.Point) []Match {
+	matches := make([]Match, len(all))
+	for i, s := range all {
+		matches[i] = s.match(offset)
+	}
+	return matches
+}
+
+// Confidence is the mean score of the matches, weighted by the ConfidenceWeights of their
+// symbols. Returns 0 if there are no matches (or all of them have a weight of 0).
+func (o *OCR) Confidence(matches []Match) float64 {
+	var sum, total float64
+	for _, m := range matches {
+		w := o.confidenceWeight(m.Symbol)
+		sum += m.G * w
+		total += w
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// confidence is the mean score of all lookups, weighted by the ConfidenceWeights of their symbols
+func (o *OCR) confidence(all []*fontSymbolLookup) float64 {
+	return o.Confidence(toMatches(all, image.Point{}))
+}
+
+func (o *OCR) confidenceWeight(symbol string) float64 {
+	if w, ok := o.ConfidenceWeights[symbol]; ok {
+		return w
+	}
+	return 1
+}
@@ -0,0 +1,106 @@
+package lookup
+
+import (
+	"image"
+	"math"
+)
+
+// Matcher scores how well a symbol matches an image with its top-left corner at a position,
+// replacing the Normalized Cross Correlation used by default. Scores must range from -1 (the
+// opposite of the symbol) to 1 (a perfect match), as they are compared with the threshold of the
+// OCR. Both images are gray scale, start at (0,0), and the symbol always fits in the image at
+// the position. Score is called concurrently by the workers of the OCR.
+type Matcher interface {
+	Score(symbol, img *image.Gray, x, y int) float64
+}
+
+// NCCMatcher is the Normalized Cross Correlation used by default to find symbols. The OCR
+// recognizes it and uses its own, much faster, implementation based on summed-area tables.
+type NCCMatcher struct{}
+
+// Score calculates the Normalized Cross Correlation of the symbol and the area of the image it
+// covers. Uniform areas, or symbols, score -1.
+func (NCCMatcher) Score(symbol, img *image.Gray, x, y int) float64 {
+	w, h := symbol.Rect.Dx(), symbol.Rect.Dy()
+	var sumS, sumI float64
+	for sy := 0; sy < h; sy++ {
+		for sx := 0; sx < w; sx++ {
+			sumS += float64(symbol.Pix[sy*symbol.Stride+sx])
+			sumI += float64(img.Pix[(y+sy)*img.Stride+x+sx])
+		}
+	}
+	n := float64(w * h)
+	meanS, meanI := sumS/n, sumI/n
+
+	var num, devS, devI float64
+	for sy := 0; sy < h; sy++ {
+		for sx := 0; sx < w; sx++ {
+			s := float64(symbol.Pix[sy*symbol.Stride+sx]) - meanS
+			i := float64(img.Pix[(y+sy)*img.Stride+x+sx]) - meanI
+			num += s * i
+			devS += s * s
+			devI += i * i
+		}
+	}
+	if devS < minDev2n || devI < minDev2n {
+		return -1
+	}
+	return math.Max(-1, math.Min(1, num/math.Sqrt(devS*devI)))
+}
+
+// DifferenceMatcher scores the mean absolute difference of the pixels of the symbol and the area
+// of the image it covers, mapped to 1 for identical pixels and -1 for opposite ones. Unlike the
+// Normalized Cross Correlation, it is sensitive to changes in brightness and contrast, but it is
+// not fooled by areas with the same shape and a different color.
+type DifferenceMatcher struct{}
+
+// Score calculates the mean absolute difference of the symbol and the area of the image it
+// covers, mapped to the range from -1 to 1.
+func (DifferenceMatcher) Score(symbol, img *image.Gray, x, y int) float64 {
+	w, h := symbol.Rect.Dx(), symbol.Rect.Dy()
+	sum := 0
+	for sy := 0; sy < h; sy++ {
+		for sx := 0; sx < w; sx++ {
+			sum += abs(int(symbol.Pix[sy*symbol.Stride+sx]) - int(img.Pix[(y+sy)*img.Stride+x+sx]))
+		}
+	}
+	return 1 - 2*float64(sum)/float64(w*h*255)
+}
+
+// ToleranceMatcher works like DifferenceMatcher, but ignoring differences of up to Tolerance gray
+// levels on each pixel, and only counting the excess of bigger ones. The anti-aliased edges of
+// glyphs, which usually differ by a few levels between renderings, are only partially penalized.
+type ToleranceMatcher struct {
+	Tolerance uint8
+}
+
+// Score calculates the mean absolute difference, beyond the tolerance, of the symbol and the area
+// of the image it covers, mapped to the range from -1 to 1.
+func (m ToleranceMatcher) Score(symbol, img *image.Gray, x, y int) float64 {
+	if m.Tolerance == 255 {
+		return 1
+	}
+	w, h := symbol.Rect.Dx(), symbol.Rect.Dy()
+	sum := 0
+	for sy := 0; sy < h; sy++ {
+		for sx := 0; sx < w; sx++ {
+			d := abs(int(symbol.Pix[sy*symbol.Stride+sx]) - int(img.Pix[(y+sy)*img.Stride+x+sx]))
+			sum += max(d-int(m.Tolerance), 0)
+		}
+	}
+	return 1 - 2*float64(sum)/float64(w*h*(255-int(m.Tolerance)))
+}
+
+// matchAll works like lookupAll, but scoring the positions with the matcher. Like lookupAll, it
+// finds nothing, without calling the matcher, when the symbol is bigger than the area searched
+func matchAll(matcher Matcher, img *image.Gray, x1, y1, x2, y2 int, symbol *image.Gray, m float64) []GPoint {
+	var list []GPoint
+	for x := x1; x <= x2-symbol.Rect.Dx()+1; x++ {
+		for y := y1; y <= y2-symbol.Rect.Dy()+1; y++ {
+			if g := matcher.Score(symbol, img, x, y); g >= m {
+				list = append(list, GPoint{X: x, Y: y, G: g})
+			}
+		}
+	}
+	return list
+}
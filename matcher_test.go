@@ -0,0 +1,112 @@
+package lookup
+
+import (
+	"image"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// countingMatcher counts the positions scored by another matcher
+type countingMatcher struct {
+	Matcher
+	calls int64
+}
+
+func (m *countingMatcher) Score(symbol, img *image.Gray, x, y int) float64 {
+	atomic.AddInt64(&m.calls, 1)
+	return m.Matcher.Score(symbol, img, x, y)
+}
+
+func TestMatcher(t *testing.T) {
+	Convey("Given an image and a symbol", t, func() {
+		img := loadImageGray("testdata/test3.png").(*image.Gray)
+		three := loadImageGray("testdata/font_1/3.png").(*image.Gray)
+		fs := NewFontSymbol("3", three)
+
+		Convey("The NCCMatcher scores like the OCR", func() {
+			for _, p := range []image.Point{{6, 4}, {12, 27}, {30, 10}, {0, 0}} {
+				expected, _ := ScoreAt(img, fs, p.X, p.Y)
+				So(NCCMatcher{}.Score(three, img, p.X, p.Y), ShouldAlmostEqual, expected, 0.000001)
+			}
+		})
+
+		Convey("The ToleranceMatcher ignores differences within the tolerance", func() {
+			noisy := image.NewGray(img.Bounds())
+			for i, v := range img.Pix {
+				noisy.Pix[i] = uint8(min(max(int(v)-3+i%7, 0), 255))
+			}
+			So(ToleranceMatcher{Tolerance: 3}.Score(three, noisy, 6, 4), ShouldAlmostEqual, 1.0)
+			So(DifferenceMatcher{}.Score(three, noisy, 6, 4), ShouldBeLessThan, 1.0)
+			So(ToleranceMatcher{}.Score(three, img, 30, 10), ShouldAlmostEqual, DifferenceMatcher{}.Score(three, img, 30, 10))
+			So(ToleranceMatcher{Tolerance: 255}.Score(three, img, 30, 10), ShouldEqual, 1.0)
+		})
+
+		Convey("It doesn't score a symbol bigger than the area searched", func() {
+			matcher := &countingMatcher{Matcher: NCCMatcher{}}
+			small := image.NewGray(image.Rect(0, 0, three.Rect.Dx()-1, three.Rect.Dy()))
+			So(matchAll(matcher, small, 0, 0, small.Rect.Dx()-1, small.Rect.Dy()-1, three, -1), ShouldBeEmpty)
+			So(matchAll(matcher, img, 6, 4, 6+three.Rect.Dx()-1, 4+three.Rect.Dy()-2, three, -1), ShouldBeEmpty)
+			So(matcher.calls, ShouldEqual, 0)
+			So(matchAll(matcher, img, 6, 4, 6+three.Rect.Dx()-1, 4+three.Rect.Dy()-1, three, -1), ShouldHaveLength, 1)
+		})
+
+		Convey("The DifferenceMatcher scores 1 for identical pixels", func() {
+			So(DifferenceMatcher{}.Score(three, img, 6, 4), ShouldAlmostEqual, 1.0)
+			So(DifferenceMatcher{}.Score(three, img, 30, 10), ShouldBeLessThan, 1.0)
+		})
+	})
+
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8, 4)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It recognizes the same text with the NCCMatcher", func() {
+			ocr.Matcher = NCCMatcher{}
+			text, err := ocr.Recognize(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It scores the positions with a custom matcher", func() {
+			matcher := &countingMatcher{Matcher: NCCMatcher{}}
+			ocr.Matcher = matcher
+			text, err := ocr.Recognize(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			So(matcher.calls, ShouldBeGreaterThan, 0)
+		})
+
+		Convey("It recognizes nothing in an image smaller than the symbols", func() {
+			matcher := &countingMatcher{Matcher: NCCMatcher{}}
+			ocr.Matcher = matcher
+			text, err := ocr.Recognize(image.NewGray(image.Rect(0, 0, 3, 3)))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "")
+			So(matcher.calls, ShouldEqual, 0)
+		})
+
+		Convey("It is stricter with the DifferenceMatcher", func() {
+			ocr := NewOCR(0.95, 4)
+			_ = ocr.LoadFont("testdata/font_1")
+			ocr.Matcher = DifferenceMatcher{}
+			text, err := ocr.Recognize(img)
+			So(err, ShouldBeNil)
+			// the first '6' differs slightly from the font, and only the NCC tolerates it
+			So(text, ShouldEqual, "3 62\n3 2€/€")
+		})
+
+		Convey("It recognizes the differing symbol with the ToleranceMatcher", func() {
+			ocr.Matcher = DifferenceMatcher{}
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3 62\n3 2€/€")
+
+			ocr.Matcher = ToleranceMatcher{Tolerance: 16}
+			text, err := ocr.Recognize(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+	})
+}
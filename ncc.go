@@ -60,20 +60,25 @@ func lookup(img *imageBinary, template *imageBinary, x int, y int, m float64) (*
 	return &GPoint{X: x, Y: y, G: g}, nil
 }
 
+// minDev2n is the minimum (not normalized) variance of an area for it not to be considered
+// uniform. Below it, the rounding errors of the integral images dominate, and the score of a
+// match would be meaningless (or even NaN or infinite)
+const minDev2n = 0.5
+
 func gamma(img *imageBinaryChannel, template *imageBinaryChannel, xx int, yy int) float64 {
-	d := denominator(img, template, xx, yy)
-	if d == 0 {
+	di := img.dev2nRect(xx, yy, xx+template.width-1, yy+template.height-1)
+	dt := template.dev2n()
+	if di < minDev2n || dt < minDev2n {
 		return -1
 	}
 
 	n := numerator(img, template, xx, yy)
-	return n / d
-}
-
-func denominator(img *imageBinaryChannel, template *imageBinaryChannel, xx int, yy int) float64 {
-	di := img.dev2nRect(xx, yy, xx+template.width-1, yy+template.height-1)
-	dt := template.dev2n()
-	return math.Sqrt(di * dt)
+	g := n / math.Sqrt(di*dt)
+	if math.IsNaN(g) {
+		return -1
+	}
+	// rounding errors can push the score slightly out of its range
+	return math.Max(-1, math.Min(1, g))
 }
 
 func numerator(img *imageBinaryChannel, template *imageBinaryChannel, offsetX int, offsetY int) float64 {
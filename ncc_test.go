@@ -3,6 +3,7 @@ package lookup
 import (
 	"image"
 	_ "image/png"
+	"math"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -24,6 +25,33 @@ func TestNumerator(t *testing.T) {
 	})
 }
 
+func TestGamma(t *testing.T) {
+	Convey("Given a template with a sparse glyph", t, func() {
+		glyph := image.NewGray(image.Rect(0, 0, 5, 5))
+		glyph.Pix[12] = 255
+		template := newImageBinaryChannel(glyph, gray)
+
+		Convey("It scores -1 in a uniform region of the image", func() {
+			img := image.NewGray(image.Rect(0, 0, 300, 300))
+			for i := range img.Pix {
+				img.Pix[i] = 251
+			}
+			img.Pix[0] = 0
+			ib := newImageBinaryChannel(img, gray)
+			for _, p := range []image.Point{{100, 100}, {250, 250}, {295, 295}} {
+				g := gamma(ib, template, p.X, p.Y)
+				So(math.IsNaN(g), ShouldBeFalse)
+				So(g, ShouldEqual, -1)
+			}
+		})
+
+		Convey("It never scores above 1", func() {
+			ib := newImageBinaryChannel(glyph, gray)
+			So(gamma(ib, template, 0, 0), ShouldBeBetweenOrEqual, 0.999, 1)
+		})
+	})
+}
+
 var (
 	benchImg         = loadImageColor("testdata/cyclopst1.png")
 	benchTemplate    = loadImageColor("testdata/cyclopst3.png")
@@ -1,13 +1,39 @@
 package lookup
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"image"
+	"io"
+	"io/fs"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
+// OtsuThreshold is the BinaryThreshold that picks the threshold of each image with Otsu's method,
+// which best separates its pixels in two classes: background and ink.
+const OtsuThreshold = -1
+
+// ErrNoSymbols is returned when recognizing text without any symbol to search for, like when no
+// font was loaded, to tell it apart from an image without text. OCRs with a Fallback can have no
+// symbols of their own.
+var ErrNoSymbols = errors.New("no symbols to recognize the text with")
+
+// checkSymbols returns ErrNoSymbols if there are no symbols to search for
+func checkSymbols(symbols []*FontSymbol) error {
+	if len(symbols) == 0 {
+		return ErrNoSymbols
+	}
+	return nil
+}
+
 // OCR implements a simple OCR based on the Lookup functions. It allows multiple fontsets,
 // just call LoadFont for each fontset.
 //
@@ -17,14 +43,268 @@ import (
 //
 // Sometimes you need to specify two different image for one symbol (if image / font symbol vary
 // too much). To do so add unicode ZERO WIDTH SPACE symbol (%E2%80%8B) to the filename.
-// Ex: %2F%E2%80%8B.png will produce '/' symbol as well.
+// Ex: %2F%E2%80%8B.png will produce '/' symbol as well. Symbols created programmatically are
+// alternatives in the same way when they share the same symbol string, see AddSymbolVariant.
 type OCR struct {
 	fontFamilies map[string][]*FontSymbol
-	threshold    float64
-	allSymbols   []*FontSymbol
+	familyDPI    map[string]float64
+	// symbolFamily is the font family each symbol was added to. It is kept in the OCR, instead of
+	// in the symbols, as the same symbols can be shared by several OCRs
+	symbolFamily map[*FontSymbol]string
+	// rtlFamilies are the font families marked by SetFamilyRTL as right-to-left, or not
+	rtlFamilies map[string]bool
+
+	threshold  float64
+	allSymbols []*FontSymbol
+	// looseSymbols are the symbols added with AddSymbols, not in any font family, kept apart to
+	// rebuild allSymbols when a family is removed
+	looseSymbols []*FontSymbol
 	numThreads   int
+
+	// UnknownGlyph, when not empty, is written in any gap between two recognized symbols that
+	// contains ink that no symbol matched, followed by the spaces the gap is wide enough for. Set
+	// it to something like "\uFFFD" to make missing symbols visible in the text, instead of
+	// silently omitting them
+	UnknownGlyph string
+
+	// Deterministic makes the recognition always produce the same output for the same input,
+	// by breaking all ties between candidate symbols using their position and symbol. Without
+	// it, the order candidates are found in (which varies when using multiple threads) can
+	// affect which of two equally good symbols is kept
+	Deterministic bool
+
+	// ThresholdSteps, when set, replaces the threshold with a series of decreasing thresholds.
+	// Symbols are first accepted using the highest one, and each lower step is only used in
+	// the regions of the image not covered by symbols already accepted. This allows easy
+	// text to be matched confidently, while harder text still gets a chance with a lower bar
+	ThresholdSteps []float64
+
+	// Mirrored makes the OCR also search for horizontally flipped versions of all symbols,
+	// recognizing text seen through a mirror. Flipped symbols are reported with their original
+	// symbol
+	Mirrored bool
+
+	// FamilyWeights multiplies the score of the symbols of each font family (by name) when
+	// deciding which of two overlapping symbols to keep. Use it to favor a trusted family over
+	// a generic fallback one. Families not present in the map have a weight of 1
+	FamilyWeights map[string]float64
+
+	// ExpandLigatures maps symbols to the text written in their place in the recognized text.
+	// This allows matching ligatures (like "ﬁ") as a single symbol, while still outputting
+	// their component characters ("fi")
+	ExpandLigatures map[string]string
+
+	// MaxLineSymbols and MaxLineWidth (in pixels), when greater than zero, limit the size of a
+	// line of text. When a line reaches any of them, it is broken at the next space between
+	// symbols. This guards against separate lines that could not be told apart being merged
+	MaxLineSymbols int
+	MaxLineWidth   int
+
+	// LineSegmentation groups the symbols in lines by their vertical overlap, instead of starting
+	// a new line each time a symbol is found to the left of the previous one. Lines are read top
+	// to bottom, and symbols left to right inside them, so lines that wrap to the right of the
+	// previous one, or columns of text, are kept apart. A gap between lines at least as tall
+	// as their symbols writes an empty line for each such height, keeping paragraphs apart
+	LineSegmentation bool
+
+	// ProportionalSpaces makes gaps between symbols produce as many spaces as the number of
+	// symbol advances that fit in them (rounded), instead of a single space. Useful to keep
+	// text aligned in columns
+	ProportionalSpaces bool
+
+	// AcceptFunc, if set, is called for every candidate symbol found with a score above the
+	// threshold, before overlapping candidates are removed. Candidates for which it returns false
+	// are discarded. Use it to apply domain rules, like only accepting digits in some region
+	AcceptFunc func(Match) bool
+
+	// MinMatches is the minimum number of symbols that must be recognized for any text to be
+	// returned. If fewer symbols are found, the text is considered noise and an empty string is
+	// returned instead. Default is 0 (any match is returned)
+	MinMatches int
+
+	// SubPixelSteps, when greater than 1, makes symbols also be searched shifted by fractions
+	// of a pixel, in steps of 1/SubPixelSteps on both axes, keeping the best match. Recovers
+	// small glyphs that fail only because they are not aligned to the pixel grid of the font.
+	// The number of templates searched grows quadratically with the steps
+	SubPixelSteps int
+
+	// StretchX and StretchY are horizontal and vertical scale factors symbols are also searched
+	// with, on top of their original size, recovering condensed or expanded text rendered from
+	// the same font. Every combination of both axes is tried, so {0.9} and nil search each
+	// symbol also 10% narrower, and {0.9, 1.1} and {0.9} search 5 extra sizes. Factors of 1 are
+	// ignored, as symbols are always searched with their original size
+	StretchX, StretchY []float64
+
+	// Scales are factors symbols are also searched with, scaled in both axes, on top of their
+	// original size. Use it when the text of the images can be rendered at a different size (or
+	// DPI) than the font was loaded at, e.g. {0.8, 0.9, 1.1, 1.25}. The best scoring size wins
+	// where several overlap, and its match covers the size the symbol was found at. They combine
+	// with StretchX and StretchY, multiplying the factors of both axes
+	Scales []float64
+
+	// TransitionCost, if set, returns the penalty of having the symbol next right after prev on
+	// the same line. Where overlapping candidates compete for a position, the ones making the
+	// text with the best total score, minus the transition costs, are chosen. Use it to bias the
+	// recognition of structured texts, like codes where letters are always followed by digits
+	TransitionCost func(prev, next string) float64
+
+	// AspectRatioPenalty, when positive, lowers the score of candidates whose ink has a different
+	// aspect ratio than the ink of the symbol, by the penalty times the absolute logarithm of the
+	// ratio between both. Candidates falling below the threshold are discarded. Removes matches
+	// that are geometrically implausible, like a tall glyph matching a flat region
+	AspectRatioPenalty float64
+
+	// OverlapRatio, when positive, makes overlapping symbols only remove each other when more
+	// than that fraction of the area of the smaller one is covered by the other. By default
+	// any overlap is enough, which removes small symbols, like diacritics, that legitimately sit
+	// inside the area of a bigger one. Setting it to 1 disables the removal of overlapping symbols
+	OverlapRatio float64
+
+	// PreserveLeadingSpace makes each line start with as many spaces as symbol advances fit
+	// between the left edge of the image and its first symbol. By default leading space is
+	// dropped. Use it to keep the indentation of text recognized in a cropped region
+	PreserveLeadingSpace bool
+
+	// Fallback, if set, is another OCR (usually with a bigger and slower fontset) used to
+	// recognize again the lines of text recognized with a confidence (mean score of their
+	// symbols) below FallbackConfidence, or the whole image if nothing was recognized. This
+	// gives the speed of a small fontset, with the accuracy of the big one where needed. The
+	// Fallback scans the image as prepared by this OCR, so its own ContrastTile and
+	// BinaryThreshold are not applied. Recognition fails if the Fallback, or any Fallback of
+	// it, is this OCR. It is used by Recognize and its variants returning the same text, like
+	// RecognizeRegion, RecognizeTimed or RecognizeLayout, even if this OCR has no symbols. The
+	// methods returning the matches, or output built from them, like RecognizeDetailed,
+	// RecognizeResult or RecognizeHOCR, don't use it, and fail with ErrNoSymbols without symbols.
+	// RecognizeRobust, RecognizeAutoOrient and RecognizeRotations use it for the text, but need
+	// symbols of their own to compare the confidence of their attempts
+	Fallback           *OCR
+	FallbackConfidence float64
+
+	// ScoreMargin, when positive, is how much the score of a symbol must exceed the score of the
+	// best candidate of a different symbol overlapping it. Symbols without a clear winner in
+	// their area are considered ambiguous and discarded
+	ScoreMargin float64
+
+	// DebugFamilyPrefix prefixes every symbol in the recognized text with the name of its font
+	// family, like "arial:H arial:i", separating adjacent symbols with a space. It is a
+	// diagnostic aid to see which family wins the matches in an image
+	DebugFamilyPrefix bool
+
+	// Delimited makes the recognized text enclose every symbol in "|", like "|H|i| |t|h|e|r|e|",
+	// so the exact sequence of symbols matched can be recovered, even for symbols of more than
+	// one rune. Spaces between symbols are enclosed too, and any "|" or "\" in the text of a
+	// symbol is escaped with a "\"
+	Delimited bool
+
+	// ExpectedGlyphs, when greater than zero, stops the search for symbols as soon as candidates
+	// were found in that many places, without searching for the remaining symbols. This lowers
+	// the latency for short texts of a known length, at the cost of accuracy: a symbol not yet
+	// searched can't replace a worse one already found. Use it with a high threshold
+	ExpectedGlyphs int
+
+	// Certainty, when greater than zero, settles the area of any candidate scoring at least
+	// Certainty: the symbols searched after it was found are not tried at the positions where
+	// they would overlap it. This saves time on clean images, where symbols match almost
+	// perfectly, like 0.98 for text rendered with the same font. It changes the results when a
+	// symbol scores above Certainty where a better one would be found. The symbols are settled in
+	// the order they are searched in, so the results don't depend on the threads
+	Certainty float64
+
+	// ConfidenceWeights sets the weight of the score of each symbol in the confidence of a text
+	// (the weighted mean of the scores of its symbols), used by Confidence and wherever the OCR
+	// gates on confidence. Use it so that a low score on an unimportant symbol, like a separator,
+	// doesn't drag down the confidence. Symbols not present in the map have a weight of 1
+	ConfidenceWeights map[string]float64
+
+	// ContrastTile, when greater than zero, normalizes the contrast of the image locally before
+	// recognizing it, equalizing the histogram of tiles of ContrastTile x ContrastTile pixels
+	// (CLAHE). This evens out images where a part is faded, without blowing out the rest
+	ContrastTile int
+
+	// BinaryThreshold, when greater than zero, binarizes the image before recognizing it (after
+	// ContrastTile): pixels at least as bright as BinaryThreshold (1 to 255) become white, and the
+	// rest black. Lower it for faded images, like thermal printer receipts, so the lighter pixels
+	// of the glyphs count as much as the darker ones. Set it to OtsuThreshold to pick it from
+	// the histogram of each image. Other negative values make recognition fail. Symbols are not
+	// binarized, so anti-aliased fonts may need a lower threshold to match binarized text
+	BinaryThreshold int
+
+	// AlphaOnly makes the OCR detect images defined only by their alpha channel (all their pixels
+	// of the same color, like black glyphs over a transparent background) and use their alpha as
+	// intensity, as converting them to gray scale ignoring the alpha leaves them blank. It is off
+	// by default, as the detection reads the pixels of every image that is neither gray nor opaque
+	AlphaOnly bool
+
+	// SpaceTolerance is how many pixels a gap between symbols can fall short of the advance of
+	// the symbols and still be a space. Negative values require gaps wider than the advance. Use
+	// it to move the boundary away from the gaps of a font that are close to its advance, so
+	// similar images don't randomly gain or lose spaces. Default is 0
+	SpaceTolerance int
+
+	// SpaceFactor multiplies the advance a gap between two symbols needs to be a space. Values
+	// below 1 make spaces more eager, recovering the spaces of condensed fonts, and values above
+	// 1 more conservative, avoiding spurious spaces in fonts with a wide tracking. It is applied
+	// before SpaceTolerance. Default is 1 (zero is also taken as 1)
+	SpaceFactor float64
+
+	// SameLabelPolicy decides what happens when symbols with the same label, but from different
+	// font families, match overlapping areas. By default the one with the best score is kept
+	SameLabelPolicy SameLabelPolicy
+
+	// FamilyPriority lists the font families (by name) from the most to the least preferred,
+	// for SameLabelPriorityFamily. Families not in the list come after all of the listed ones
+	FamilyPriority []string
+
+	// MaxImagePixels, when greater than zero, is the largest image (in number of pixels) that
+	// can be recognized, returning an error for bigger ones instead of preparing them. Preparing
+	// an image for recognition takes about 25 bytes per pixel
+	MaxImagePixels int
+
+	// MaxCandidates, when greater than zero, is the maximum number of candidates (matches before
+	// removing the overlapping ones) a recognition can find. Once exceeded, the search stops and
+	// an error is returned. Together with MaxImagePixels, it bounds the memory used by a call,
+	// even for images crafted to match the symbols almost everywhere
+	MaxCandidates int
+
+	// MinLineConfidence, when greater than zero, drops the lines of text whose confidence is
+	// below it, removing the garbage lines noise can produce while keeping the good lines
+	// intact. The confidence of a line is the mean score of its symbols (see Confidence), or
+	// their median score with LineConfidenceMedian
+	MinLineConfidence float64
+
+	// LineConfidenceMedian makes MinLineConfidence use the median score of the symbols of a line,
+	// so a few badly matched symbols don't cause a good line to be dropped
+	LineConfidenceMedian bool
+
+	// ImageBands, when greater than 1, splits the search of each symbol in that many horizontal
+	// bands of the image, which are searched in parallel like different symbols are. Use it to
+	// keep all threads busy when searching few symbols in big images. Each position is searched
+	// in a single band, so symbols crossing the boundary between bands are found only once
+	ImageBands int
+
+	// Matcher, if set, scores how well symbols match the image, instead of the Normalized Cross
+	// Correlation (NCCMatcher) used by default. Use it to try other similarity measures, like
+	// DifferenceMatcher or ToleranceMatcher. Custom matchers are much slower than the default
+	// one, which reuses the summed-area tables of the image. It is a field, like the rest of the
+	// options, rather than a parameter of NewOCR, whose optional parameters are the threads
+	Matcher Matcher
 }
 
+// SameLabelPolicy is how the OCR chooses between symbols with the same label, from different
+// font families, that match overlapping areas of the image
+type SameLabelPolicy int
+
+const (
+	// SameLabelBestScore keeps the symbol with the best score (weighted by FamilyWeights)
+	SameLabelBestScore SameLabelPolicy = iota
+	// SameLabelPriorityFamily keeps the symbol of the family that comes first in FamilyPriority,
+	// regardless of the scores
+	SameLabelPriorityFamily
+	// SameLabelKeepAlternatives keeps the symbol with the best score, reporting the others in
+	// the Alternatives of its Match
+	SameLabelKeepAlternatives
+)
+
 // NewOCR creates a new OCR instance, that will use the given threshold. You can optionally
 // parallelize the processing by specifying the number of threads to use. The optimal number
 // varies and depends on your use case (size of fontset x size of image). Default is use
@@ -32,8 +312,12 @@ type OCR struct {
 func NewOCR(threshold float64, numThreads ...int) *OCR {
 	ocr := &OCR{
 		fontFamilies: make(map[string][]*FontSymbol),
+		familyDPI:    make(map[string]float64),
+		symbolFamily: make(map[*FontSymbol]string),
+		rtlFamilies:  make(map[string]bool),
 		threshold:    threshold,
 		numThreads:   1,
+		SpaceFactor:  1,
 	}
 
 	if len(numThreads) > 0 {
@@ -43,63 +327,895 @@ func NewOCR(threshold float64, numThreads ...int) *OCR {
 	return ocr
 }
 
+// Clone creates a copy of the OCR, with the same symbols and options, that can be changed without
+// affecting the original, like setting a different threshold or number of threads for a request
+// to a server sharing the fonts loaded once. Adding symbols or font families to either of them,
+// or setting the DPI or direction of a family, does not affect the other, as the families are
+// kept by each OCR. The FontSymbols themselves are shared, so their own setters, like
+// SetMinScore, affect both, and the maps and slices of the exported options must be treated as
+// read-only.
+func (o *OCR) Clone() *OCR {
+	c := *o
+	c.fontFamilies = make(map[string][]*FontSymbol, len(o.fontFamilies))
+	for name, family := range o.fontFamilies {
+		c.fontFamilies[name] = family[:len(family):len(family)]
+	}
+	c.familyDPI = make(map[string]float64, len(o.familyDPI))
+	for name, dpi := range o.familyDPI {
+		c.familyDPI[name] = dpi
+	}
+	c.symbolFamily = make(map[*FontSymbol]string, len(o.symbolFamily))
+	for s, name := range o.symbolFamily {
+		c.symbolFamily[s] = name
+	}
+	c.rtlFamilies = make(map[string]bool, len(o.rtlFamilies))
+	for name, rtl := range o.rtlFamilies {
+		c.rtlFamilies[name] = rtl
+	}
+	c.allSymbols = o.allSymbols[:len(o.allSymbols):len(o.allSymbols)]
+	c.looseSymbols = o.looseSymbols[:len(o.looseSymbols):len(o.looseSymbols)]
+	return &c
+}
+
+// SetThreshold sets the minimum score, ranging from -1 to 1, symbols need to be recognized.
+func (o *OCR) SetThreshold(threshold float64) { o.threshold = threshold }
+
+// SetThreads sets the number of threads used to search for symbols. Values below 1 use one thread.
+func (o *OCR) SetThreads(numThreads int) { o.numThreads = max(numThreads, 1) }
+
 // Adds symbols associated to a certain font family.
-// Allows adding to an existing family (no checks are done to avoid duplicated symbols).
+// Allows adding to an existing family (no checks are done to avoid duplicated symbols, use
+// AddFontFamilyUnique for that). The symbols are not changed, so they can be shared with other
+// OCRs, or other families, and still be reported as part of this family by this OCR.
 func (o *OCR) AddFontFamily(name string, symbols ...*FontSymbol) {
+	for _, s := range symbols {
+		o.symbolFamily[s] = name
+	}
 	family := o.fontFamilies[name]
 	family = append(family, symbols...)
 
 	o.fontFamilies[name] = family
 
-	o.AddSymbols(symbols...)
+	o.allSymbols = append(o.allSymbols, symbols...)
+}
+
+// RemoveFontFamily removes a font family, and all its symbols, from the symbols to search, keeping
+// the symbols of the other families and the ones added with AddSymbols, even the ones also in the
+// family removed. Use it to swap fontsets without creating a new OCR. Removing a family that was
+// not added does nothing.
+func (o *OCR) RemoveFontFamily(name string) {
+	family, ok := o.fontFamilies[name]
+	if !ok {
+		return
+	}
+	delete(o.fontFamilies, name)
+	delete(o.familyDPI, name)
+	delete(o.rtlFamilies, name)
+
+	// the symbols left are the ones of the other families plus the loose ones, in the order
+	// they were added
+	names := make([]string, 0, len(o.fontFamilies))
+	for other := range o.fontFamilies {
+		names = append(names, other)
+	}
+	sort.Strings(names)
+	left := make(map[*FontSymbol]int, len(o.allSymbols))
+	for _, other := range names {
+		for _, s := range o.fontFamilies[other] {
+			left[s]++
+			if o.symbolFamily[s] == name {
+				o.symbolFamily[s] = other
+			}
+		}
+	}
+	for _, s := range o.looseSymbols {
+		left[s]++
+	}
+	for _, s := range family {
+		if o.symbolFamily[s] == name {
+			delete(o.symbolFamily, s)
+		}
+	}
+	// a new slice, as the symbols of a clone may share the same array
+	symbols := make([]*FontSymbol, 0, len(o.allSymbols))
+	for _, s := range o.allSymbols {
+		if left[s] > 0 {
+			left[s]--
+			symbols = append(symbols, s)
+		}
+	}
+	o.allSymbols = symbols
+}
+
+// addLoadedFamily adds symbols just loaded by the OCR, not shared with anyone yet, to a font
+// family, also naming the family in the symbols, so it is kept when saving them to a font pack
+func (o *OCR) addLoadedFamily(name string, symbols []*FontSymbol) {
+	for _, s := range symbols {
+		s.family = name
+	}
+	o.AddFontFamily(name, symbols...)
+}
+
+// family returns the font family a symbol (or the one it is a variant of) was added to
+func (o *OCR) family(s *FontSymbol) string {
+	if name, ok := o.symbolFamily[s.original()]; ok {
+		return name
+	}
+	return s.family
+}
+
+// familySymbols returns the symbols with the font family they were added to in this OCR, and
+// the direction set for the family by SetFamilyRTL, using a variant for the ones that differ, so
+// the symbols themselves are never changed
+func (o *OCR) familySymbols(symbols []*FontSymbol) []*FontSymbol {
+	var named []*FontSymbol
+	for i, s := range symbols {
+		family, rtl := o.family(s), s.rtl
+		if familyRTL, ok := o.rtlFamilies[family]; ok {
+			rtl = familyRTL
+		}
+		if family == s.family && rtl == s.rtl {
+			if named != nil {
+				named = append(named, s)
+			}
+			continue
+		}
+		if named == nil {
+			named = append(make([]*FontSymbol, 0, len(symbols)), symbols[:i]...)
+		}
+		v := *s
+		v.base = s.original()
+		v.family = family
+		v.rtl = rtl
+		named = append(named, &v)
+	}
+	if named == nil {
+		return symbols
+	}
+	return named
+}
+
+// AddFontFamilyUnique works like AddFontFamily, but skips the symbols identical (with the same
+// label and image) to one already in the family, or earlier in symbols. Use it to reload a fontset
+// without duplicating its symbols. Returns the number of symbols added.
+func (o *OCR) AddFontFamilyUnique(name string, symbols ...*FontSymbol) int {
+	known := make(map[uint64][]*FontSymbol)
+	for _, s := range o.fontFamilies[name] {
+		h := s.hash()
+		known[h] = append(known[h], s)
+	}
+
+	var unique []*FontSymbol
+	for _, s := range symbols {
+		h := s.hash()
+		duplicated := false
+		for _, k := range known[h] {
+			if s.identical(k) {
+				duplicated = true
+				break
+			}
+		}
+		if !duplicated {
+			known[h] = append(known[h], s)
+			unique = append(unique, s)
+		}
+	}
+	o.AddFontFamily(name, unique...)
+	return len(unique)
+}
+
+// WithScales sets the Scales symbols are also searched with, and returns the OCR to allow
+// chaining it to NewOCR
+func (o *OCR) WithScales(scales []float64) *OCR {
+	o.Scales = scales
+	return o
+}
+
+// SetFamilyDPI records the resolution (in dots per inch) the symbols of a font family were rendered
+// at. It is used by RecognizeDPI to scale the symbols to the resolution of the image. Returns an
+// error, keeping any resolution set before, if dpi is not greater than zero.
+func (o *OCR) SetFamilyDPI(name string, dpi float64) error {
+	if !(dpi > 0) {
+		return fmt.Errorf("invalid DPI %v for font family %q", dpi, name)
+	}
+	o.familyDPI[name] = dpi
+	return nil
+}
+
+// SetFamilyRTL marks all symbols of a font family as belonging to a right-to-left script (or not),
+// overriding what is set in the symbols themselves, which are not changed. See FontSymbol.SetRTL.
+func (o *OCR) SetFamilyRTL(name string, rtl bool) {
+	o.rtlFamilies[name] = rtl
 }
 
 // Adds symbols not associated to a specific font family.
+// Several symbols can have the same label, for symbols rendered in more than one way. All of
+// them are searched, and when more than one match the same area, the best one is kept.
 func (o *OCR) AddSymbols(symbols ...*FontSymbol) {
+	o.looseSymbols = append(o.looseSymbols, symbols...)
 	o.allSymbols = append(o.allSymbols, symbols...)
 }
 
+// AddSymbolVariant adds another image for a symbol, not associated to a specific font family.
+// Like the images loaded with a ZERO WIDTH SPACE in their file name, any symbols with the same
+// symbol string are alternatives: all of them are searched, and any of them matching produces
+// the same text. Returns the symbol created.
+func (o *OCR) AddSymbolVariant(symbol string, img image.Image) *FontSymbol {
+	fs := NewFontSymbol(symbol, img)
+	o.AddSymbols(fs)
+	return fs
+}
+
+// AddSymbolGroup adds symbols that are alternative images of the same symbol, like renderings
+// that vary with sub-pixel positioning. All symbols are relabeled with the given symbol, so any of
+// them matching produces it. When more than one of them match the same area, the one with the
+// best score is kept, regardless of their sizes.
+func (o *OCR) AddSymbolGroup(symbol string, symbols ...*FontSymbol) {
+	for _, s := range symbols {
+		s.symbol = symbol
+	}
+	o.AddSymbols(symbols...)
+}
+
 // LoadFont loads a specific fontset from the given folder. Fonts are simple image files
 // containing a PNG/JPEG of the font, and named after the "letter" represented by the image.
 //
 // This can be called multiple times, with different folders, to load different fontsets.
 func (o *OCR) LoadFont(fontPath string) error {
-	if _, err := os.Stat(fontPath); os.IsNotExist(err) {
+	symbols, err := loadFont(fontPath)
+	if err != nil {
+		return err
+	}
+
+	o.addLoadedFamily(filepath.Base(fontPath), symbols)
+	return nil
+}
+
+// LoadFontFS works like LoadFont, but reads the fontset from the folder dir of the given file
+// system, like the one of a go:embed directive, so fonts can be bundled inside the binary.
+// The font family is named after the base name of dir.
+func (o *OCR) LoadFontFS(fsys fs.FS, dir string) error {
+	symbols, err := loadFontFS(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	familyName := path.Base(dir)
+	o.addLoadedFamily(familyName, symbols)
+	return nil
+}
+
+// LoadFontAsync works like LoadFont, but loads the symbol files in parallel, using the number of
+// threads of the OCR, calling progress (if not nil) after each file is loaded with the number of
+// files loaded so far and the total. Calls to progress are never concurrent. It returns once the
+// whole font is loaded, so call it from a goroutine to keep the application responsive while
+// showing the progress of big fontsets.
+func (o *OCR) LoadFontAsync(fontPath string, progress func(done, total int)) error {
+	symbols, err := loadFontParallel(os.DirFS(fontPath), ".", o.numThreads, progress)
+	if err != nil {
 		return err
 	}
 
+	o.addLoadedFamily(filepath.Base(fontPath), symbols)
+	return nil
+}
+
+// LoadFontJSON loads a fontset from a JSON object, mapping each symbol to its base64 encoded image,
+// like {"0": "iVBORw0KGgo...", "1": "iVBORw0KGgo..."}. As with LoadFont, ZERO WIDTH SPACEs in
+// the symbols are removed, so more than one image can be specified for the same symbol.
+//
+// The symbols are not associated to any font family.
+func (o *OCR) LoadFontJSON(r io.Reader) error {
+	symbols, err := loadFontJSON(r)
+	if err != nil {
+		return err
+	}
+
+	o.AddSymbols(symbols...)
+	return nil
+}
+
+// LoadFontBM loads a bitmap font in the BMFont format, described by a text descriptor (.fnt)
+// and a single texture atlas. The symbols are cut from the atlas, using the advances in the
+// descriptor for spacing, and added to a font family named after the face of the font.
+func (o *OCR) LoadFontBM(fntPath, texturePath string) error {
+	symbols, face, err := loadFontBM(fntPath, texturePath)
+	if err != nil {
+		return err
+	}
+
+	o.addLoadedFamily(face, symbols)
+	return nil
+}
+
+// LoadFontWeight loads a fontset from the given folder into an existing (or new) font family,
+// marking all its symbols with the given weight. Use it to keep, for example, the regular and
+// bold variants of a font under the same family name.
+func (o *OCR) LoadFontWeight(fontPath string, familyName string, weight FontWeight) error {
 	symbols, err := loadFont(fontPath)
 	if err != nil {
 		return err
 	}
 
-	familyName := filepath.Base(fontPath)
-	o.AddFontFamily(familyName, symbols...)
+	for _, s := range symbols {
+		s.weight = weight
+	}
+	o.addLoadedFamily(familyName, symbols)
 	return nil
 }
 
 // Recognize the text in the image using the fontsets previously loaded. If a SubImage
 // is received, the search will be limited by the boundaries of the SubImage
 func (o *OCR) Recognize(img image.Image) (string, error) {
-	bi := newImageBinary(ensureGrayScale(img))
-	return o.recognize(bi, image.Rect(0, 0, bi.width-1, bi.height-1))
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+	return o.recognize(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols)
 }
 
-func (o *OCR) recognize(bi *imageBinary, rect image.Rectangle) (string, error) {
-	found, err := findAllInParallel(o.numThreads, o.allSymbols, bi, o.threshold, rect)
+// RecognizeRegion recognizes the text inside the region r of the image, without the need of a
+// SubImage. The region is clamped to the image bounds, and an error is returned if it ends up empty.
+func (o *OCR) RecognizeRegion(img image.Image, r image.Rectangle) (string, error) {
+	rect, err := scanRect(img.Bounds(), r)
 	if err != nil {
 		return "", err
 	}
 
-	if len(found) == 0 {
-		return "", nil
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
 	}
+	return o.recognize(context.Background(), bi, rect, o.allSymbols)
+}
 
-	text := o.filterAndArrange(found)
-	return text, nil
+// RecognizeContext works like Recognize, but stops as soon as the context is done, returning
+// its error. The search for each symbol is not interrupted, so it may take as long as searching
+// for the slowest symbol to return.
+func (o *OCR) RecognizeContext(ctx context.Context, img image.Image) (string, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+	return o.recognize(ctx, bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols)
 }
 
-func biggerFirst(list []*fontSymbolLookup) func(i, j int) bool {
+// RecognizeTimeout works like Recognize, but gives up on the search once the duration passes,
+// counted from the call, so including the preparation of the image, like for a frame of a live
+// video that went stale. On timeout, it returns the text of the matches found until then,
+// arranged as usual, with context.DeadlineExceeded. That text is a best effort: the symbols not
+// searched yet are missing, and a match that overlaps a better one not found yet is kept. The
+// Fallback is not used on timeout.
+func (o *OCR) RecognizeTimeout(img image.Image, d time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+
+	rect := image.Rect(0, 0, bi.width-1, bi.height-1)
+	if o.fallbackOnly(o.allSymbols) {
+		return o.recognizeWithFallback(ctx, bi, rect, nil)
+	}
+	all, err := o.detect(ctx, bi, rect, o.allSymbols, true)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return o.arrange(bi, all), err
+	}
+	if err != nil {
+		return "", err
+	}
+	return o.arrangeWithFallback(ctx, bi, rect, all)
+}
+
+// RecognizeGray recognizes the text in a gray scale image. As the image is already gray, it
+// skips the conversion to gray scale done by Recognize, which is faster. The preprocessing set in
+// the OCR, like ContrastTile and BinaryThreshold, is still applied
+func (o *OCR) RecognizeGray(img *image.Gray) (string, error) {
+	text, _, err := o.recognizeGray(grayAtOrigin(img), img.Bounds().Min)
+	return text, err
+}
+
+// RecognizeParallel recognizes the text in the image like Recognize, but using the given number
+// of threads instead of the ones the OCR was created with. The OCR is not modified, so it is
+// safe to use concurrently with other calls.
+func (o *OCR) RecognizeParallel(img image.Image, threads int) (string, error) {
+	if threads < 1 {
+		return "", fmt.Errorf("invalid number of threads %d", threads)
+	}
+	c := *o
+	c.numThreads = threads
+	return c.Recognize(img)
+}
+
+// RecognizeTop recognizes only the text in the top part of the image, up to the given height.
+// Useful for headers and titles, as the rest of the image is not scanned at all.
+func (o *OCR) RecognizeTop(img image.Image, height int) (string, error) {
+	if height <= 0 {
+		return "", fmt.Errorf("invalid height %d", height)
+	}
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+	return o.recognize(context.Background(), bi, image.Rect(0, 0, bi.width-1, min(height, bi.height)-1), o.allSymbols)
+}
+
+// RecognizeFamilies works like Recognize, but only uses the symbols of the given font families.
+// This is faster and avoids confusion with symbols of families known not to be in the image.
+func (o *OCR) RecognizeFamilies(img image.Image, families ...string) (string, error) {
+	var symbols []*FontSymbol
+	for _, name := range families {
+		family, ok := o.fontFamilies[name]
+		if !ok {
+			return "", fmt.Errorf("unknown font family %q", name)
+		}
+		symbols = append(symbols, family...)
+	}
+
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+	return o.recognize(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), symbols)
+}
+
+// RecognizeWhitelist works like Recognize, but only uses the symbols made of the characters in
+// allowed, like "0123456789" for a numeric field. This is faster, and avoids confusion with
+// symbols known not to be in the image.
+func (o *OCR) RecognizeWhitelist(img image.Image, allowed string) (string, error) {
+	return o.recognizeSymbols(img, func(r rune) bool { return strings.ContainsRune(allowed, r) })
+}
+
+// RecognizeBlacklist works like Recognize, but does not use the symbols with any of the
+// characters in denied.
+func (o *OCR) RecognizeBlacklist(img image.Image, denied string) (string, error) {
+	return o.recognizeSymbols(img, func(r rune) bool { return !strings.ContainsRune(denied, r) })
+}
+
+// recognizeSymbols recognizes the text using only the symbols with all their characters allowed
+func (o *OCR) recognizeSymbols(img image.Image, allowed func(r rune) bool) (string, error) {
+	var symbols []*FontSymbol
+	for _, s := range o.allSymbols {
+		if strings.IndexFunc(s.symbol, func(r rune) bool { return !allowed(r) }) < 0 {
+			symbols = append(symbols, s)
+		}
+	}
+
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+	return o.recognize(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), symbols)
+}
+
+// SymbolTiming is the time spent searching for a symbol in an image.
+type SymbolTiming struct {
+	Symbol   *FontSymbol
+	Duration time.Duration
+}
+
+// RecognizeTimed works like Recognize, but also returns the time spent searching for each symbol,
+// slowest first. The time spent on the variants of a symbol (like mirrored ones) is added to the
+// symbol itself. Use it to find which symbols dominate the recognition time.
+func (o *OCR) RecognizeTimed(img image.Image) (string, []SymbolTiming, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", nil, err
+	}
+	rect := image.Rect(0, 0, bi.width-1, bi.height-1)
+	if o.fallbackOnly(o.allSymbols) {
+		text, err := o.recognizeWithFallback(context.Background(), bi, rect, nil)
+		return text, nil, err
+	}
+	if err := checkSymbols(o.allSymbols); err != nil {
+		return "", nil, err
+	}
+	f := o.newFinder(context.Background(), bi, rect, o.allSymbols)
+
+	var mu sync.Mutex
+	durations := make(map[*FontSymbol]time.Duration)
+	f.onSymbolDone = func(symbol *FontSymbol, d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		durations[symbol.original()] += d
+	}
+
+	found, err := f.lookupAll()
+	if err != nil {
+		return "", nil, err
+	}
+	text, err := o.arrangeWithFallback(context.Background(), bi, rect, o.kept(bi, o.accepted(bi, found)))
+	if err != nil {
+		return "", nil, err
+	}
+
+	timings := make([]SymbolTiming, 0, len(durations))
+	for s, d := range durations {
+		timings = append(timings, SymbolTiming{Symbol: s, Duration: d})
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		return timings[i].Duration > timings[j].Duration
+	})
+	return text, timings, nil
+}
+
+// RecognizeProgress works like Recognize, but calls onProgress (if not nil) each time the search
+// of a symbol is done, with the number of searches done so far and the total. With ImageBands,
+// each band of a symbol counts as a search. Calls to onProgress are never concurrent, and
+// done grows by one with each call, but they are made from the goroutines doing the search, so
+// onProgress should return quickly. The total may not be reached if the search stops early,
+// like when ExpectedGlyphs are found. Use it to show the progress of big images.
+func (o *OCR) RecognizeProgress(img image.Image, onProgress func(done, total int)) (string, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+	rect := image.Rect(0, 0, bi.width-1, bi.height-1)
+	if o.fallbackOnly(o.allSymbols) {
+		return o.recognizeWithFallback(context.Background(), bi, rect, nil)
+	}
+	if err := checkSymbols(o.allSymbols); err != nil {
+		return "", err
+	}
+	f := o.newFinder(context.Background(), bi, rect, o.allSymbols)
+
+	if onProgress != nil {
+		var mu sync.Mutex
+		done, total := 0, f.numTasks()
+		f.onSymbolDone = func(*FontSymbol, time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			onProgress(done, total)
+		}
+	}
+
+	found, err := f.lookupAll()
+	if err != nil {
+		return "", err
+	}
+	return o.arrangeWithFallback(context.Background(), bi, rect, o.kept(bi, o.accepted(bi, found)))
+}
+
+// MatchesInRegion returns the symbols found inside the region r of the image, after removing the
+// overlapping ones, but without arranging them in reading order. Use it to implement custom
+// layouts. The region is clamped to the image bounds, and an error is returned if it ends up empty.
+func (o *OCR) MatchesInRegion(img image.Image, r image.Rectangle) ([]Match, error) {
+	rect, err := scanRect(img.Bounds(), r)
+	if err != nil {
+		return nil, err
+	}
+
+	bi, err := o.prepare(img)
+	if err != nil {
+		return nil, err
+	}
+	found, err := o.find(context.Background(), bi, rect, o.allSymbols, false)
+	if err != nil {
+		return nil, err
+	}
+	return toMatches(o.dedup(found), bi.offset), nil
+}
+
+// scanRect converts a region of an image to the area to be scanned in its imageBinary, which
+// always starts at (0,0) and has the bottom-right corner included in the area
+func scanRect(bounds image.Rectangle, r image.Rectangle) (image.Rectangle, error) {
+	r = r.Intersect(bounds)
+	if r.Empty() {
+		return image.Rectangle{}, fmt.Errorf("region %v is empty or outside the image bounds %v", r, bounds)
+	}
+	r = r.Sub(bounds.Min)
+	r.Max = r.Max.Sub(image.Point{X: 1, Y: 1})
+	return r, nil
+}
+
+// RecognizeDPI works like Recognize, for an image with the given resolution (in dots per inch).
+// The symbols of each font family with a DPI set by SetFamilyDPI are scaled to match the image
+// resolution. Symbols of other families are used as they are.
+func (o *OCR) RecognizeDPI(img image.Image, dpi float64) (string, error) {
+	if dpi <= 0 {
+		return "", fmt.Errorf("invalid DPI %v", dpi)
+	}
+	symbols := make([]*FontSymbol, len(o.allSymbols))
+	for i, s := range o.allSymbols {
+		symbols[i] = s
+		if familyDPI, ok := o.familyDPI[o.family(s)]; ok {
+			factor := dpi / familyDPI
+			symbols[i] = s.scaled(factor, factor)
+		}
+	}
+
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+	return o.recognize(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), symbols)
+}
+
+// RecognizeDetailed works like Recognize, but returns each recognized symbol with its position
+// and similarity score, in reading order.
+func (o *OCR) RecognizeDetailed(img image.Image) ([]Match, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return nil, err
+	}
+	all, err := o.detect(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols, false)
+	if err != nil {
+		return nil, err
+	}
+	return toMatches(all, bi.offset), nil
+}
+
+// CountGlyphs returns the number of symbols that Recognize would find in the image, without
+// building the text. Use it as a quick probe of whether (and how much) text there is.
+func (o *OCR) CountGlyphs(img image.Image) (int, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return 0, err
+	}
+	all, err := o.detect(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols, false)
+	if err != nil {
+		return 0, err
+	}
+	return len(all), nil
+}
+
+// recognize writes the text of the symbols detected inside rect. It is built from the same symbols
+// RecognizeDetailed returns, only adding the spaces and line breaks between them
+func (o *OCR) recognize(ctx context.Context, bi *imageBinary, rect image.Rectangle, symbols []*FontSymbol) (string, error) {
+	if o.fallbackOnly(symbols) {
+		return o.recognizeWithFallback(ctx, bi, rect, nil)
+	}
+	all, err := o.detect(ctx, bi, rect, symbols, false)
+	if err != nil {
+		return "", err
+	}
+	return o.arrangeWithFallback(ctx, bi, rect, all)
+}
+
+// detect returns the symbols found inside rect, after removing the overlapping ones, sorted in
+// reading order. With partial, a cancelled search returns the symbols of the candidates found
+// until then, along with the error of the context
+func (o *OCR) detect(ctx context.Context, bi *imageBinary, rect image.Rectangle, symbols []*FontSymbol, partial bool) ([]*fontSymbolLookup, error) {
+	found, err := o.find(ctx, bi, rect, symbols, partial)
+	if err != nil && found == nil {
+		return nil, err
+	}
+	return o.kept(bi, found), err
+}
+
+// kept removes the overlapping candidates, keeping the best ones, and the lines with a
+// confidence below MinLineConfidence, returning the symbols left sorted in reading order
+func (o *OCR) kept(bi *imageBinary, found []*fontSymbolLookup) []*fontSymbolLookup {
+	return o.confidentLines(bi, o.filter(found))
+}
+
+// confidentLines removes the symbols, sorted in reading order, of the lines with a confidence
+// below MinLineConfidence
+func (o *OCR) confidentLines(bi *imageBinary, all []*fontSymbolLookup) []*fontSymbolLookup {
+	if o.MinLineConfidence <= 0 {
+		return all
+	}
+	var kept []*fontSymbolLookup
+	for _, line := range o.lines(bi, all) {
+		if o.lineConfidence(line) >= o.MinLineConfidence {
+			kept = append(kept, line...)
+		}
+	}
+	return kept
+}
+
+// lineConfidence is the mean score of the symbols of a line, or the median one with
+// LineConfidenceMedian
+func (o *OCR) lineConfidence(line []*fontSymbolLookup) float64 {
+	if !o.LineConfidenceMedian {
+		return o.confidence(line)
+	}
+	scores := make([]float64, len(line))
+	for i, s := range line {
+		scores[i] = s.g
+	}
+	sort.Float64s(scores)
+	mid := len(scores) / 2
+	if len(scores)%2 == 0 {
+		return (scores[mid-1] + scores[mid]) / 2
+	}
+	return scores[mid]
+}
+
+// prepare converts the image to the imageBinary used for recognition
+func (o *OCR) prepare(img image.Image) (*imageBinary, error) {
+	if err := o.checkImageSize(img.Bounds()); err != nil {
+		return nil, err
+	}
+	normalized, err := o.normalize(grayScale(img, o.AlphaOnly))
+	if err != nil {
+		return nil, err
+	}
+	bi := newImageBinary(normalized)
+	bi.offset = img.Bounds().Min
+	return bi, nil
+}
+
+// checkImageSize returns an error if an image with the given bounds exceeds MaxImagePixels
+func (o *OCR) checkImageSize(bounds image.Rectangle) error {
+	if o.MaxImagePixels > 0 && bounds.Dx()*bounds.Dy() > o.MaxImagePixels {
+		return fmt.Errorf("image of size %v exceeds the maximum of %d pixels", bounds.Size(), o.MaxImagePixels)
+	}
+	return nil
+}
+
+// normalize applies the preprocessing configured to the gray scale image, or returns an error
+// if the BinaryThreshold is invalid
+func (o *OCR) normalize(img image.Image) (image.Image, error) {
+	if o.BinaryThreshold < 0 && o.BinaryThreshold != OtsuThreshold {
+		return nil, fmt.Errorf("invalid binary threshold %d", o.BinaryThreshold)
+	}
+	if o.ContrastTile > 0 {
+		img = equalizeContrast(img.(*image.Gray), o.ContrastTile)
+	}
+	if o.BinaryThreshold > 0 || o.BinaryThreshold == OtsuThreshold {
+		gray := img.(*image.Gray)
+		threshold := o.BinaryThreshold
+		if threshold == OtsuThreshold {
+			threshold = otsuThreshold(gray)
+		}
+		img = binarize(gray, threshold)
+	}
+	return img, nil
+}
+
+// find returns all candidates for the symbols found inside rect, before removing the overlapping
+// ones. With partial, a cancelled search returns the candidates found until then, along with the
+// error of the context
+func (o *OCR) find(ctx context.Context, bi *imageBinary, rect image.Rectangle, symbols []*FontSymbol, partial bool) ([]*fontSymbolLookup, error) {
+	if err := checkSymbols(symbols); err != nil {
+		return nil, err
+	}
+	f := o.newFinder(ctx, bi, rect, symbols)
+	f.partial = partial
+	found, err := f.lookupAll()
+	if err != nil && found == nil {
+		return nil, err
+	}
+	return o.accepted(bi, found), err
+}
+
+// accepted filters the candidates, keeping only the ones accepted by the AcceptFunc
+func (o *OCR) accepted(bi *imageBinary, found []*fontSymbolLookup) []*fontSymbolLookup {
+	found = o.aspectRatioPenalized(bi, found)
+	if o.AcceptFunc == nil {
+		return found
+	}
+	accepted := found[:0]
+	for _, l := range found {
+		if o.AcceptFunc(l.match(bi.offset)) {
+			accepted = append(accepted, l)
+		}
+	}
+	return accepted
+}
+
+func (o *OCR) newFinder(ctx context.Context, bi *imageBinary, rect image.Rectangle, symbols []*FontSymbol) *parallelFinder {
+	f := newParallelFinder(ctx, o.numThreads, o.searchSymbols(symbols), bi, o.searchThreshold(), rect)
+	f.expected = o.ExpectedGlyphs
+	f.symbolThresholds = true
+	f.maxCandidates = o.MaxCandidates
+	f.bands = o.ImageBands
+	f.certainty = o.Certainty
+	if _, ncc := o.Matcher.(NCCMatcher); !ncc {
+		f.matcher = o.Matcher
+	}
+	return f
+}
+
+// newScoringFinder creates a finder like newFinder, for all symbols, but accepting any score of
+// at least threshold, to score every position instead of finding the symbols. As most positions
+// are accepted, it doesn't stop after the ExpectedGlyphs
+func (o *OCR) newScoringFinder(ctx context.Context, bi *imageBinary, rect image.Rectangle, threshold float64) *parallelFinder {
+	f := o.newFinder(ctx, bi, rect, o.allSymbols)
+	f.threshold = threshold
+	f.expected = 0
+	return f
+}
+
+// aspectRatioPenalized applies the AspectRatioPenalty to the candidates, discarding the ones
+// that fall below the threshold
+func (o *OCR) aspectRatioPenalized(bi *imageBinary, found []*fontSymbolLookup) []*fontSymbolLookup {
+	if o.AspectRatioPenalty <= 0 {
+		return found
+	}
+	kept := found[:0]
+	for _, l := range found {
+		symbol := l.fs.image.inkBounds(0, 0, l.fs.width-1, l.fs.height-1)
+		region := bi.inkBounds(l.x, l.y, l.x+l.fs.width-1, l.y+l.fs.height-1)
+		if !symbol.Empty() && !region.Empty() {
+			ratio := float64(region.Dx()*symbol.Dy()) / float64(region.Dy()*symbol.Dx())
+			l.g -= o.AspectRatioPenalty * math.Abs(math.Log(ratio))
+			l.score = l.g
+		}
+		if l.g >= o.symbolThreshold(l.fs) {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// symbolThreshold is the minimum score a candidate of the symbol needs to be found: its own
+// MinScore, if set, or the one of the OCR
+func (o *OCR) symbolThreshold(fs *FontSymbol) float64 {
+	if fs.minScore > 0 {
+		return fs.minScore
+	}
+	return o.searchThreshold()
+}
+
+// searchSymbols expands the list of symbols with all variants that should also be searched for,
+// according to the options of the OCR
+func (o *OCR) searchSymbols(symbols []*FontSymbol) []*FontSymbol {
+	symbols = o.familySymbols(symbols)
+	if o.Mirrored {
+		expanded := make([]*FontSymbol, 0, len(symbols)*2)
+		for _, s := range symbols {
+			expanded = append(expanded, s, s.mirror())
+		}
+		symbols = expanded
+	}
+	if len(o.StretchX) > 0 || len(o.StretchY) > 0 || len(o.Scales) > 0 {
+		xs, ys := stretchFactors(o.StretchX), stretchFactors(o.StretchY)
+		scales := stretchFactors(o.Scales)
+		expanded := make([]*FontSymbol, 0, len(symbols)*len(xs)*len(ys)*len(scales))
+		for _, s := range symbols {
+			for _, k := range scales {
+				for _, sy := range ys {
+					for _, sx := range xs {
+						expanded = append(expanded, s.scaled(sx*k, sy*k))
+					}
+				}
+			}
+		}
+		symbols = expanded
+	}
+	if o.SubPixelSteps > 1 {
+		expanded := make([]*FontSymbol, 0, len(symbols)*o.SubPixelSteps*o.SubPixelSteps)
+		for _, s := range symbols {
+			for y := 0; y < o.SubPixelSteps; y++ {
+				for x := 0; x < o.SubPixelSteps; x++ {
+					dx, dy := float64(x)/float64(o.SubPixelSteps), float64(y)/float64(o.SubPixelSteps)
+					expanded = append(expanded, s.shifted(dx, dy))
+				}
+			}
+		}
+		symbols = expanded
+	}
+	return symbols
+}
+
+// stretchFactors returns the scale factors to search an axis with, always starting with 1
+func stretchFactors(stretch []float64) []float64 {
+	factors := []float64{1}
+	for _, f := range stretch {
+		if f != 1 {
+			factors = append(factors, f)
+		}
+	}
+	return factors
+}
+
+// searchThreshold is the minimum score a candidate needs to be found
+func (o *OCR) searchThreshold() float64 {
+	if len(o.ThresholdSteps) == 0 {
+		return o.threshold
+	}
+	threshold := o.ThresholdSteps[0]
+	for _, t := range o.ThresholdSteps {
+		threshold = math.Min(threshold, t)
+	}
+	return threshold
+}
+
+func biggerFirst(list []*fontSymbolLookup, deterministic bool) func(i, j int) bool {
 	maxSize := 0
 	for _, i := range list {
 		maxSize = max(maxSize, i.fs.image.size)
@@ -107,52 +1223,202 @@ func biggerFirst(list []*fontSymbolLookup) func(i, j int) bool {
 	maxSize2 := maxSize / 2
 
 	return func(i, j int) bool {
-		return list[i].biggerThan(list[j], maxSize2)
+		if list[i].biggerThan(list[j], maxSize2) {
+			return true
+		}
+		return deterministic && !list[j].biggerThan(list[i], maxSize2) && list[i].precedes(list[j])
+	}
+}
+
+// filter removes overlapping symbols, keeping the best ones, and sorts the rest in reading order
+func (o *OCR) filter(all []*fontSymbolLookup) []*fontSymbolLookup {
+	var candidates []*fontSymbolLookup
+	if o.TransitionCost != nil || o.ScoreMargin > 0 {
+		candidates = append(candidates, all...)
+	}
+
+	all = o.dedup(all)
+	if o.ScoreMargin > 0 {
+		all = o.unambiguous(all, candidates)
+	}
+	o.sortReadingOrder(all)
+
+	if o.TransitionCost != nil {
+		all = o.resolveTransitions(all, candidates)
+		o.sortReadingOrder(all)
 	}
+	return all
 }
 
-func (o *OCR) filterAndArrange(all []*fontSymbolLookup) string {
+// unambiguous removes the symbols kept that don't exceed the score of any candidate of a
+// different symbol overlapping them by the ScoreMargin
+func (o *OCR) unambiguous(kept, candidates []*fontSymbolLookup) []*fontSymbolLookup {
+	clear := kept[:0]
+	for _, k := range kept {
+		ambiguous := false
+		for _, c := range candidates {
+			if c.fs.symbol != k.fs.symbol && c.cross(k) && k.score-c.score < o.ScoreMargin {
+				ambiguous = true
+				break
+			}
+		}
+		if !ambiguous {
+			clear = append(clear, k)
+		}
+	}
+	return clear
+}
+
+// sortReadingOrder sorts the symbols top/bottom/left/right
+func (o *OCR) sortReadingOrder(all []*fontSymbolLookup) {
+	if o.LineSegmentation {
+		i := 0
+		for _, line := range o.segmentLines(all) {
+			i += copy(all[i:], line)
+		}
+		return
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].comesAfter(all[j]) {
+			return true
+		}
+		return o.Deterministic && !all[j].comesAfter(all[i]) && all[i].precedes(all[j])
+	})
+}
+
+// dedup removes overlapping symbols, keeping the best ones
+func (o *OCR) dedup(all []*fontSymbolLookup) []*fontSymbolLookup {
+	if len(all) == 0 {
+		return all
+	}
+
+	if o.FamilyWeights != nil {
+		for _, s := range all {
+			if w, ok := o.FamilyWeights[s.fs.family]; ok {
+				s.score = s.g * w
+			}
+		}
+	}
+
+	if o.Deterministic {
+		// start from the same order, regardless of the order the symbols were found in
+		sort.Slice(all, func(i, j int) bool {
+			return all[i].precedes(all[j])
+		})
+	}
+
+	if o.SameLabelPolicy == SameLabelPriorityFamily {
+		all = o.removeLowerPriorityFamilies(all)
+	}
+
+	if len(o.ThresholdSteps) > 0 {
+		return o.removeOverlapsInSteps(all)
+	}
+	return o.removeOverlaps(all)
+}
+
+func (o *OCR) removeOverlaps(all []*fontSymbolLookup) []*fontSymbolLookup {
 	// big images eat small ones
-	sort.Slice(all, biggerFirst(all))
+	sort.Slice(all, biggerFirst(all, o.Deterministic))
 	for k, kk := range all {
 		for j := k + 1; j < len(all); j++ {
 			jj := all[j]
-			if kk.cross(jj) {
+			if o.overlap(kk, jj) {
+				if o.SameLabelPolicy == SameLabelKeepAlternatives && sameLabelOtherFamily(kk, jj) {
+					kk.alternatives = append(kk.alternatives, jj)
+				}
 				all = deleteSymbol(all, j)
 				j--
 			}
 		}
 	}
+	return all
+}
 
-	// sort top/bottom/left/right
-	sort.Slice(all, func(i, j int) bool {
-		return all[i].comesAfter(all[j])
-	})
+// removeLowerPriorityFamilies removes the symbols overlapping another one with the same label
+// from a font family that comes before theirs in FamilyPriority. It is done before removing the
+// rest of the overlaps by size, so the family decides regardless of the order of the symbols
+func (o *OCR) removeLowerPriorityFamilies(all []*fontSymbolLookup) []*fontSymbolLookup {
+	rank := func(family string) int {
+		for i, f := range o.FamilyPriority {
+			if f == family {
+				return i
+			}
+		}
+		return len(o.FamilyPriority)
+	}
+	var kept []*fontSymbolLookup
+	for _, l := range all {
+		preferred := false
+		for _, f := range all {
+			if sameLabelOtherFamily(l, f) && rank(f.fs.family) < rank(l.fs.family) && o.overlap(l, f) {
+				preferred = true
+				break
+			}
+		}
+		if !preferred {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
 
-	var str strings.Builder
-	x := all[0].x
-	previousAdvance := 0
-	for i, s := range all {
-		// if distance between end of previous symbol and beginning of the
-		// current is larger then a char size, then it is a space
-		// This should not be applied in the beginning (i == 0) as it would put a white space for
-		// any s.x > maxCX will have a (useless) whitespace in front
-		maxCurrentPreviousAdvance := max(previousAdvance, s.fs.Advance())
-		if s.x-x >= maxCurrentPreviousAdvance && i != 0 {
-			str.WriteString(" ")
+// sameLabelOtherFamily checks if two lookups are of symbols with the same label, from different
+// font families
+func sameLabelOtherFamily(l, f *fontSymbolLookup) bool {
+	return l.fs.symbol == f.fs.symbol && l.fs.family != f.fs.family
+}
+
+// removeOverlapsInSteps accepts the symbols in stages, from the highest threshold step to the
+// lowest. Symbols of a stage are only considered where no symbol was accepted in a previous one
+func (o *OCR) removeOverlapsInSteps(all []*fontSymbolLookup) []*fontSymbolLookup {
+	steps := append([]float64{}, o.ThresholdSteps...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(steps)))
+
+	var accepted []*fontSymbolLookup
+	for _, step := range steps {
+		var candidates []*fontSymbolLookup
+		for _, s := range all {
+			if s.g >= step && !o.overlapsAny(s, accepted) {
+				candidates = append(candidates, s)
+			}
 		}
+		accepted = append(accepted, o.removeOverlaps(candidates)...)
+	}
+	return accepted
+}
 
-		// if we drop back, then we have an end of line
-		if s.x < x {
-			str.WriteString("\n")
+func crossesAny(s *fontSymbolLookup, list []*fontSymbolLookup) bool {
+	for _, l := range list {
+		if l.cross(s) {
+			return true
 		}
+	}
+	return false
+}
 
-		x = s.x + s.fs.Advance()
-		previousAdvance = s.fs.Advance()
-		str.WriteString(s.fs.symbol)
+// overlap checks if the symbols overlap enough for one of them to be removed, according to the
+// OverlapRatio
+func (o *OCR) overlap(l, f *fontSymbolLookup) bool {
+	if o.OverlapRatio <= 0 {
+		return l.cross(f)
+	}
+	r, r2 := l.bounds(), f.bounds()
+	covered := r.Intersect(r2)
+	if covered.Empty() {
+		return false
 	}
+	smaller := min(r.Dx()*r.Dy(), r2.Dx()*r2.Dy())
+	return float64(covered.Dx()*covered.Dy()) > o.OverlapRatio*float64(smaller)
+}
 
-	return str.String()
+func (o *OCR) overlapsAny(s *fontSymbolLookup, list []*fontSymbolLookup) bool {
+	for _, l := range list {
+		if o.overlap(l, s) {
+			return true
+		}
+	}
+	return false
 }
 
 func deleteSymbol(all []*fontSymbolLookup, i int) []*fontSymbolLookup {
@@ -0,0 +1,84 @@
+package lookup
+
+import (
+	"context"
+	"image"
+)
+
+// RecognizeBestEffort works like Recognize, but never leaves ink without a symbol: for each group
+// of connected ink pixels not covered by any symbol recognized, the symbol that best matches it is
+// written, even if its score is below the threshold. Use it when a best guess is better than no
+// text at all.
+func (o *OCR) RecognizeBestEffort(img image.Image) (string, error) {
+	bi, all, err := o.bestEffort(img)
+	if err != nil {
+		return "", err
+	}
+	return o.arrange(bi, all), nil
+}
+
+// RecognizeBestEffortDetailed works like RecognizeBestEffort, but returns the symbols recognized,
+// like RecognizeDetailed. The best guesses, scoring below the threshold, are flagged with
+// LowConfidence.
+func (o *OCR) RecognizeBestEffortDetailed(img image.Image) ([]Match, error) {
+	bi, all, err := o.bestEffort(img)
+	if err != nil {
+		return nil, err
+	}
+	return toMatches(all, bi.offset), nil
+}
+
+// bestEffort returns the symbols recognized in the image, along with the best guesses for the
+// ink not covered by them, sorted in reading order
+func (o *OCR) bestEffort(img image.Image) (*imageBinary, []*fontSymbolLookup, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return nil, nil, err
+	}
+	rect := image.Rect(0, 0, bi.width-1, bi.height-1)
+	all, err := o.detect(context.Background(), bi, rect, o.allSymbols, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// pad by the largest symbol searched, including the scaled and stretched variants
+	width, height := 0, 0
+	for _, s := range o.searchSymbols(o.allSymbols) {
+		width, height = max(width, s.width), max(height, s.height)
+	}
+	for _, c := range inkComponents(grayScale(img, o.AlphaOnly).(*image.Gray)) {
+		center := c.Min.Add(c.Max).Div(2)
+		if coveredAt(all, center) {
+			continue
+		}
+
+		// scan every position where a symbol could cover the center of the ink, accepting any score
+		around := image.Rect(center.X-width, center.Y-height, center.X+width, center.Y+height).Intersect(rect)
+		found, err := o.newScoringFinder(context.Background(), bi, around, -1).lookupAll()
+		if err != nil {
+			return nil, nil, err
+		}
+		var best *fontSymbolLookup
+		for _, l := range found {
+			if coveredAt([]*fontSymbolLookup{l}, center) && (best == nil || l.g > best.g || l.g == best.g && l.precedes(best)) {
+				best = l
+			}
+		}
+		if best != nil {
+			best.lowConfidence = true
+			all = append(all, best)
+		}
+	}
+	o.sortReadingOrder(all)
+	return bi, all, nil
+}
+
+// coveredAt checks if any of the symbols covers the point
+func coveredAt(all []*fontSymbolLookup, p image.Point) bool {
+	for _, s := range all {
+		if p.In(image.Rect(s.x, s.y, s.x+s.fs.width, s.y+s.fs.height)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package lookup
+
+import (
+	"image"
+	_ "image/png"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOCRRecognizeBestEffort(t *testing.T) {
+	Convey("Given an OCR with a threshold above the score of some symbols", t, func() {
+		// the '6' at (15,4) scores 0.88
+		ocr := NewOCR(0.9)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("Recognize misses those symbols", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3 62\n3 2€/€")
+		})
+
+		Convey("It writes the best guess for them", func() {
+			text, err := ocr.RecognizeBestEffort(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It flags the best guesses as low confidence", func() {
+			matches, err := ocr.RecognizeBestEffortDetailed(img)
+			So(err, ShouldBeNil)
+			So(matches, ShouldHaveLength, 9)
+			for i, m := range matches {
+				So(m.LowConfidence, ShouldEqual, i == 1)
+			}
+			So(matches[1].Symbol, ShouldEqual, "6")
+			So(matches[1].G, ShouldBeLessThan, 0.9)
+		})
+	})
+
+	Convey("Given an OCR searching the symbols scaled, bigger than the ones loaded", t, func() {
+		ocr := NewOCR(0.98)
+		_ = ocr.LoadFont("testdata/font_1")
+		ocr.WithScales([]float64{2.5})
+		img := scale(loadImageGray("testdata/test3.png").(*image.Gray), 2.5, 2.5)
+
+		Convey("It writes the best guess among the scaled symbols", func() {
+			text, err := ocr.RecognizeBestEffort(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+	})
+
+	Convey("Given an image without ink", t, func() {
+		ocr := NewOCR(0.9)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It returns no text", func() {
+			text, err := ocr.RecognizeBestEffort(newGrayImage(20, 20, nil))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "")
+		})
+	})
+}
@@ -0,0 +1,269 @@
+package lookup
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DiffKind is the kind of difference between the recognized and the expected text.
+type DiffKind int
+
+const (
+	// A symbol was recognized in place of a different expected one
+	DiffSubstitution DiffKind = iota
+	// A symbol was recognized where none was expected
+	DiffInsertion
+	// An expected symbol was not recognized
+	DiffDeletion
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffSubstitution:
+		return "substitution"
+	case DiffInsertion:
+		return "insertion"
+	case DiffDeletion:
+		return "deletion"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// Diff is a difference between the symbols recognized in an image and the expected ones.
+type Diff struct {
+	Kind DiffKind
+	// Position of the expected symbol, counting only symbols (whitespace is ignored). For
+	// insertions, it is the position of the expected symbol the extra symbol was found before
+	Position int
+	// The symbol expected. Empty for insertions
+	Expected string
+	// The symbol recognized. Empty for deletions
+	Found string
+	// The area of the recognized symbol in the image. Empty for deletions
+	Rect image.Rectangle
+}
+
+// Compare recognizes the text in the image and compares it to the expected text, symbol by
+// symbol, reporting the differences found. Whitespace is ignored in the comparison. Returns an
+// empty list if the image contains exactly the expected text.
+func (o *OCR) Compare(img image.Image, expected string) ([]Diff, error) {
+	matches, err := o.RecognizeDetailed(img)
+	if err != nil {
+		return nil, err
+	}
+	return o.diff(matches, expected), nil
+}
+
+// RecognizeExpected recognizes the text in the image, checking it against the expected text.
+// Returns the text recognized, and the expected symbols that were not recognized in their place
+// (aligning both texts as Compare does), in order. Use it to check that no required text is
+// missing from an image.
+func (o *OCR) RecognizeExpected(img image.Image, expected string) (string, []string, error) {
+	result, err := o.RecognizeResult(img)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var missing []string
+	for _, d := range o.diff(result.Matches, expected) {
+		if d.Kind != DiffInsertion {
+			missing = append(missing, d.Expected)
+		}
+	}
+	return result.Text, missing, nil
+}
+
+// diff aligns the matches, in reading order, to the expected text, returning the differences
+func (o *OCR) diff(matches []Match, expected string) []Diff {
+	want := o.tokenize(expected)
+	got := make([]string, len(matches))
+	for i, m := range matches {
+		got[i] = m.Symbol
+	}
+
+	var diffs []Diff
+	position := 0
+	for _, step := range align(want, got) {
+		switch {
+		case step.expected < 0:
+			m := matches[step.found]
+			diffs = append(diffs, Diff{Kind: DiffInsertion, Position: position, Found: m.Symbol, Rect: m.Rect})
+			continue
+		case step.found < 0:
+			diffs = append(diffs, Diff{Kind: DiffDeletion, Position: position, Expected: want[step.expected]})
+		case want[step.expected] != got[step.found]:
+			m := matches[step.found]
+			diffs = append(diffs, Diff{Kind: DiffSubstitution, Position: position, Expected: want[step.expected], Found: m.Symbol, Rect: m.Rect})
+		}
+		position++
+	}
+	return diffs
+}
+
+// tokenize splits the text in symbols, preferring the longest symbol loaded in the OCR that
+// matches at each position, and falling back to single runes. Whitespace is skipped.
+func (o *OCR) tokenize(text string) []string {
+	known := o.knownSymbols()
+	maxLen := 1
+	for s := range known {
+		maxLen = max(maxLen, len(s))
+	}
+
+	var tokens []string
+	for len(text) > 0 {
+		r, size := utf8.DecodeRuneInString(text)
+		if unicode.IsSpace(r) {
+			text = text[size:]
+			continue
+		}
+		for l := min(maxLen, len(text)); l > size; l-- {
+			if known[text[:l]] && strings.IndexFunc(text[:l], unicode.IsSpace) < 0 {
+				size = l
+				break
+			}
+		}
+		tokens = append(tokens, text[:size])
+		text = text[size:]
+	}
+	return tokens
+}
+
+// knownSymbols returns the set of symbols loaded in the OCR
+func (o *OCR) knownSymbols() map[string]bool {
+	known := make(map[string]bool)
+	for _, s := range o.allSymbols {
+		known[s.symbol] = true
+	}
+	return known
+}
+
+// CanRepresent checks if the text can be recognized with the symbols loaded, returning the
+// symbols of the text that are missing, in the order they first appear. Whitespace is ignored.
+// Use it before comparing or aligning texts, to tell a missing symbol apart from a
+// recognition failure.
+func (o *OCR) CanRepresent(text string) (missing []string) {
+	known := o.knownSymbols()
+	for _, t := range o.tokenize(text) {
+		if !known[t] {
+			missing = append(missing, t)
+			known[t] = true
+		}
+	}
+	return missing
+}
+
+// alignStep pairs a position of the expected sequence with a position of the found one. Any
+// of them is -1 when the symbol has no counterpart in the other sequence
+type alignStep struct {
+	expected, found int
+}
+
+// align finds the alignment of the two sequences with the least number of edits (substitutions,
+// insertions and deletions), using the Levenshtein distance
+func align(expected, found []string) []alignStep {
+	n, m := len(expected), len(found)
+	dist := make([][]int, n+1)
+	for i := range dist {
+		dist[i] = make([]int, m+1)
+		dist[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		dist[0][j] = j
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			cost := 1
+			if expected[i-1] == found[j-1] {
+				cost = 0
+			}
+			dist[i][j] = min(dist[i-1][j-1]+cost, min(dist[i-1][j], dist[i][j-1])+1)
+		}
+	}
+
+	steps := make([]alignStep, 0, max(n, m))
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && dist[i][j] == dist[i-1][j-1]+boolToInt(expected[i-1] != found[j-1]):
+			i, j = i-1, j-1
+			steps = append(steps, alignStep{i, j})
+		case i > 0 && dist[i][j] == dist[i-1][j]+1:
+			i--
+			steps = append(steps, alignStep{i, -1})
+		default:
+			j--
+			steps = append(steps, alignStep{-1, j})
+		}
+	}
+	for l, r := 0, len(steps)-1; l < r; l, r = l+1, r-1 {
+		steps[l], steps[r] = steps[r], steps[l]
+	}
+	return steps
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Word is an expected word aligned to the symbols recognized in an image.
+type Word struct {
+	// The expected word
+	Text string
+	// The area covered by the symbols aligned to the word. Empty if none was recognized
+	Rect image.Rectangle
+	// The fraction of the symbols of the word that were recognized in place, from 0 to 1
+	Score float64
+}
+
+// ForceAlign finds the position of each of the expected words in the image, in the given order.
+// Rather than taking the recognized text as is, the recognized symbols are aligned with the words
+// with the least number of edits, so a few misrecognized symbols don't prevent finding a word.
+// Returns a Word for each word given, with a Score of 0 for the words not found at all.
+func (o *OCR) ForceAlign(img image.Image, words []string) ([]Word, error) {
+	matches, err := o.RecognizeDetailed(img)
+	if err != nil {
+		return nil, err
+	}
+
+	var want []string
+	var wordOf []int
+	sizes := make([]int, len(words))
+	aligned := make([]Word, len(words))
+	for i, w := range words {
+		aligned[i].Text = w
+		tokens := o.tokenize(w)
+		sizes[i] = len(tokens)
+		want = append(want, tokens...)
+		for range tokens {
+			wordOf = append(wordOf, i)
+		}
+	}
+	got := make([]string, len(matches))
+	for i, m := range matches {
+		got[i] = m.Symbol
+	}
+
+	matched := make([]int, len(words))
+	for _, step := range align(want, got) {
+		if step.expected < 0 || step.found < 0 {
+			continue
+		}
+		w := &aligned[wordOf[step.expected]]
+		w.Rect = w.Rect.Union(matches[step.found].Rect)
+		if want[step.expected] == got[step.found] {
+			matched[wordOf[step.expected]]++
+		}
+	}
+	for i, n := range sizes {
+		if n > 0 {
+			aligned[i].Score = float64(matched[i]) / float64(n)
+		}
+	}
+	return aligned, nil
+}
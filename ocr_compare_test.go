@@ -0,0 +1,118 @@
+package lookup
+
+import (
+	"image"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOCRCompare(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It reports no differences when the text matches, ignoring whitespace", func() {
+			diffs, err := ocr.Compare(img, "3662 32€/€")
+			So(err, ShouldBeNil)
+			So(diffs, ShouldBeEmpty)
+		})
+
+		Convey("It reports substitutions and insertions with their positions", func() {
+			diffs, err := ocr.Compare(img, "3672\n32€€")
+			So(err, ShouldBeNil)
+			So(diffs, ShouldHaveLength, 2)
+
+			So(diffs[0].Kind, ShouldEqual, DiffSubstitution)
+			So(diffs[0].Position, ShouldEqual, 2)
+			So(diffs[0].Expected, ShouldEqual, "7")
+			So(diffs[0].Found, ShouldEqual, "6")
+
+			So(diffs[1].Kind, ShouldEqual, DiffInsertion)
+			So(diffs[1].Position, ShouldEqual, 7)
+			So(diffs[1].Found, ShouldEqual, "/")
+			So(diffs[1].Rect.Min.X, ShouldEqual, 60)
+			So(diffs[1].Rect.Min.Y, ShouldEqual, 27)
+		})
+
+		Convey("It reports deletions of expected symbols not found", func() {
+			diffs, err := ocr.Compare(img, "3662 32€/€3")
+			So(err, ShouldBeNil)
+			So(diffs, ShouldResemble, []Diff{{Kind: DiffDeletion, Position: 9, Expected: "3"}})
+		})
+	})
+}
+
+func TestOCRForceAlign(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It finds the position of each word", func() {
+			words, err := ocr.ForceAlign(img, []string{"3662", "3", "2€/€"})
+			So(err, ShouldBeNil)
+			So(words, ShouldResemble, []Word{
+				{Text: "3662", Rect: image.Rect(6, 4, 47, 18), Score: 1},
+				{Text: "3", Rect: image.Rect(12, 27, 21, 41), Score: 1},
+				{Text: "2€/€", Rect: image.Rect(33, 25, 79, 41), Score: 1},
+			})
+		})
+
+		Convey("It aligns words with misrecognized symbols", func() {
+			words, err := ocr.ForceAlign(img, []string{"3662", "32€7€"})
+			So(err, ShouldBeNil)
+			So(words[1].Rect, ShouldResemble, image.Rect(12, 25, 79, 41))
+			So(words[1].Score, ShouldEqual, 0.8)
+		})
+
+		Convey("It reports the words not found", func() {
+			words, err := ocr.ForceAlign(img, []string{"3662", "32€/€", "2"})
+			So(err, ShouldBeNil)
+			So(words[2].Rect.Empty(), ShouldBeTrue)
+			So(words[2].Score, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestOCRCanRepresent(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It reports nothing missing for a text made of loaded symbols", func() {
+			So(ocr.CanRepresent("3662 3 2€/€"), ShouldBeEmpty)
+		})
+
+		Convey("It reports each missing symbol once, in order", func() {
+			So(ocr.CanRepresent("3a2 b\ta"), ShouldResemble, []string{"a", "b"})
+		})
+
+		Convey("It recognizes symbols of more than one rune", func() {
+			ocr.AddSymbols(NewFontSymbol("ab", newGrayImage(2, 2, nil)))
+			So(ocr.CanRepresent("3ab2c"), ShouldResemble, []string{"c"})
+		})
+	})
+}
+
+func TestOCRRecognizeExpected(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It reports nothing missing when all expected symbols are found", func() {
+			text, missing, err := ocr.RecognizeExpected(img, "3662 32€")
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			So(missing, ShouldBeEmpty)
+		})
+
+		Convey("It reports the expected symbols not found in their place", func() {
+			_, missing, err := ocr.RecognizeExpected(img, "3672 32€/€ 9")
+			So(err, ShouldBeNil)
+			So(missing, ShouldResemble, []string{"7", "9"})
+		})
+	})
+}
@@ -0,0 +1,140 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ConfidenceMap returns a gray scale image, with the same bounds as img, showing the best score
+// any symbol achieved over each pixel. Brighter pixels mean higher scores, with black being a
+// score of 0 or less. Useful to visualize where the OCR is confident and where it struggles.
+//
+// All positions of all symbols are evaluated, regardless of the threshold, so this is slower
+// than Recognize.
+func (o *OCR) ConfidenceMap(img image.Image) (image.Image, error) {
+	if err := checkSymbols(o.allSymbols); err != nil {
+		return nil, err
+	}
+	bi, err := o.prepare(img)
+	if err != nil {
+		return nil, err
+	}
+	rect := image.Rect(0, 0, bi.width-1, bi.height-1)
+	found, err := o.newScoringFinder(context.Background(), bi, rect, 0).lookupAll()
+	if err != nil {
+		return nil, err
+	}
+
+	best := make([]float64, bi.width*bi.height)
+	for _, l := range found {
+		for y := l.y; y < l.y+l.fs.height; y++ {
+			for x := l.x; x < l.x+l.fs.width; x++ {
+				i := y*bi.width + x
+				best[i] = math.Max(best[i], l.g)
+			}
+		}
+	}
+
+	heatmap := image.NewGray(img.Bounds())
+	for i, g := range best {
+		heatmap.Pix[i] = uint8(math.Round(math.Min(g, 1) * 255))
+	}
+	return heatmap, nil
+}
+
+// SymbolLess reports whether symbol a should be listed before symbol b
+type SymbolLess func(a, b *FontSymbol) bool
+
+// SortByLabel lists symbols by the text they represent, and then by their font family
+func SortByLabel(a, b *FontSymbol) bool {
+	if a.symbol != b.symbol {
+		return a.symbol < b.symbol
+	}
+	return a.family < b.family
+}
+
+// SortBySize lists symbols from the smallest to the biggest image, and then by label
+func SortBySize(a, b *FontSymbol) bool {
+	if a.width*a.height != b.width*b.height {
+		return a.width*a.height < b.width*b.height
+	}
+	return SortByLabel(a, b)
+}
+
+// Symbols lists all symbols loaded in the OCR, sorted with less. If less is nil, the symbols
+// are listed in the order they were loaded, which for LoadFont is the order of the files in the
+// folder. less sees each symbol with the font family it was added to in this OCR.
+func (o *OCR) Symbols(less SymbolLess) []*FontSymbol {
+	symbols := make([]*FontSymbol, len(o.allSymbols))
+	copy(symbols, o.allSymbols)
+	if less != nil {
+		// sort the symbols and their named variants together, so less compares the families
+		// o.family reports, while the symbols returned are still the ones loaded
+		named := o.familySymbols(o.allSymbols)
+		order := make([]int, len(symbols))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return less(named[order[i]], named[order[j]]) })
+		for i, k := range order {
+			symbols[i] = o.allSymbols[k]
+		}
+	}
+	return symbols
+}
+
+const (
+	// contactSheetColumns is the number of symbols in each row of a contact sheet
+	contactSheetColumns = 16
+	// contactSheetPadding is the space, in pixels, around each symbol of a contact sheet
+	contactSheetPadding = 2
+	// contactSheetBackground is the gray level of the space between the symbols of a contact sheet
+	contactSheetBackground = 128
+)
+
+// ContactSheet returns a gray scale image with the images of all symbols loaded in the OCR, laid
+// out in a grid in the order Symbols(less) lists them, left to right and top to bottom. Useful to
+// audit a fontset, as a sheet sorted with SortByLabel shows duplicated or mislabeled symbols next
+// to each other.
+func (o *OCR) ContactSheet(less SymbolLess) image.Image {
+	symbols := o.Symbols(less)
+	cellWidth, cellHeight := 0, 0
+	for _, s := range symbols {
+		cellWidth = max(cellWidth, s.width+2*contactSheetPadding)
+		cellHeight = max(cellHeight, s.height+2*contactSheetPadding)
+	}
+	cols := min(len(symbols), contactSheetColumns)
+	rows := (len(symbols) + contactSheetColumns - 1) / contactSheetColumns
+
+	sheet := image.NewGray(image.Rect(0, 0, cols*cellWidth, rows*cellHeight))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(color.Gray{Y: contactSheetBackground}), image.Point{}, draw.Src)
+	for i, s := range symbols {
+		x := i%contactSheetColumns*cellWidth + contactSheetPadding
+		y := i/contactSheetColumns*cellHeight + contactSheetPadding
+		draw.Draw(sheet, image.Rect(x, y, x+s.width, y+s.height), s.image.gray(), image.Point{}, draw.Src)
+	}
+	return sheet
+}
+
+// String describes the configuration of the OCR: its threshold, number of threads, the number of
+// symbols loaded and how many of them belong to each font family, sorted by name. Useful for
+// logging, or to attach to bug reports.
+func (o *OCR) String() string {
+	names := make([]string, 0, len(o.fontFamilies))
+	for name := range o.fontFamilies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	families := make([]string, len(names))
+	for i, name := range names {
+		families[i] = fmt.Sprintf("%q: %d", name, len(o.fontFamilies[name]))
+	}
+	return fmt.Sprintf("OCR{threshold: %g, threads: %d, symbols: %d, families: {%s}}",
+		o.threshold, o.numThreads, len(o.allSymbols), strings.Join(families, ", "))
+}
@@ -0,0 +1,143 @@
+package lookup
+
+import (
+	"fmt"
+	"image"
+	_ "image/png"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfidenceMap(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("When I create the confidence map of an image", func() {
+			img := loadImageColor("testdata/test3.png")
+			heatmap, err := ocr.ConfidenceMap(img)
+			So(err, ShouldBeNil)
+			gray := heatmap.(*image.Gray)
+
+			Convey("It has the same bounds as the image", func() {
+				So(gray.Bounds(), ShouldResemble, img.Bounds())
+			})
+
+			Convey("It is brightest where symbols match perfectly", func() {
+				// inside the '/' found at (60,27)
+				So(gray.GrayAt(65, 33).Y, ShouldEqual, 255)
+			})
+
+			Convey("It is darker where no symbol matches well", func() {
+				So(gray.GrayAt(83, 0).Y, ShouldBeLessThan, 200)
+			})
+		})
+	})
+}
+
+func TestOCRSymbols(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		labels := func(symbols []*FontSymbol) []string {
+			var names []string
+			for _, s := range symbols {
+				names = append(names, s.String())
+			}
+			return names
+		}
+
+		Convey("Without a sort, it lists the symbols in the order they were loaded", func() {
+			So(labels(ocr.Symbols(nil)), ShouldResemble, []string{"/", "€", "€", "0", "1", "2", "3", "4", "5", "6", "7", "8", "9"})
+		})
+
+		Convey("It lists the symbols sorted by label", func() {
+			So(labels(ocr.Symbols(SortByLabel)), ShouldResemble, []string{"/", "0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "€", "€"})
+		})
+
+		Convey("It lists the symbols sorted by size", func() {
+			symbols := ocr.Symbols(SortBySize)
+			for i := 1; i < len(symbols); i++ {
+				So(symbols[i-1].width*symbols[i-1].height, ShouldBeLessThanOrEqualTo, symbols[i].width*symbols[i].height)
+			}
+		})
+
+		Convey("It does not change the order used for recognition", func() {
+			_ = ocr.Symbols(SortByLabel)
+			So(labels(ocr.allSymbols)[0], ShouldEqual, "/")
+		})
+	})
+
+	Convey("Given an OCR with the same label in two font families", t, func() {
+		ocr := NewOCR(0.8)
+		img := image.NewGray(image.Rect(0, 0, 3, 3))
+		b, a := NewFontSymbol("x", img), NewFontSymbol("x", img)
+		ocr.AddFontFamily("b", b)
+		ocr.AddFontFamily("a", a)
+
+		Convey("It sorts by the family each symbol was added to", func() {
+			symbols := ocr.Symbols(SortByLabel)
+			So(symbols, ShouldHaveLength, 2)
+			So(symbols[0], ShouldPointTo, a)
+			So(symbols[1], ShouldPointTo, b)
+		})
+	})
+}
+
+func TestOCRContactSheet(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("When I create a contact sheet sorted by label", func() {
+			sheet := ocr.ContactSheet(SortByLabel).(*image.Gray)
+			symbols := ocr.Symbols(SortByLabel)
+
+			Convey("It has a single row with room for all symbols", func() {
+				tallest := 0
+				for _, s := range symbols {
+					tallest = max(tallest, s.height)
+				}
+				So(sheet.Bounds().Dy(), ShouldEqual, tallest+2*contactSheetPadding)
+				So(sheet.Bounds().Dx()%len(symbols), ShouldEqual, 0)
+			})
+
+			Convey("It draws the symbols in the order they are listed", func() {
+				cellWidth := sheet.Bounds().Dx() / len(symbols)
+				for i, s := range symbols {
+					x := i*cellWidth + contactSheetPadding
+					cell := sheet.SubImage(image.Rect(x, contactSheetPadding, x+s.width, contactSheetPadding+s.height))
+					g, _ := ScoreAt(cell, s, x, contactSheetPadding)
+					So(g, ShouldAlmostEqual, 1.0, 0.000001)
+				}
+			})
+		})
+
+		Convey("When no symbols are loaded", func() {
+			sheet := NewOCR(0.8).ContactSheet(nil)
+
+			Convey("It returns an empty image", func() {
+				So(sheet.Bounds().Empty(), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestOCRString(t *testing.T) {
+	Convey("Given an OCR without symbols", t, func() {
+		ocr := NewOCR(0.8, 2)
+
+		Convey("It describes its configuration", func() {
+			So(ocr.String(), ShouldEqual, "OCR{threshold: 0.8, threads: 2, symbols: 0, families: {}}")
+		})
+
+		Convey("It describes the symbols of each family, sorted by name", func() {
+			_ = ocr.LoadFont("testdata/font_1")
+			ocr.AddFontFamily("a", NewFontSymbol("x", image.NewGray(image.Rect(0, 0, 4, 4))))
+			ocr.AddSymbols(NewFontSymbol("y", image.NewGray(image.Rect(0, 0, 4, 4))))
+			So(ocr.String(), ShouldEqual, `OCR{threshold: 0.8, threads: 2, symbols: 15, families: {"a": 1, "font_1": 13}}`)
+			So(fmt.Sprint(ocr), ShouldEqual, ocr.String())
+		})
+	})
+}
@@ -0,0 +1,137 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"image"
+	"strings"
+)
+
+// errFallbackCycle is returned when recognizing with an OCR that is its own Fallback, directly or
+// through the Fallback of its Fallback
+var errFallbackCycle = errors.New("the Fallback of the OCR falls back to the OCR itself")
+
+// checkFallback checks that following the Fallbacks from the OCR never gets back to an OCR
+// already visited, which would recognize forever
+func (o *OCR) checkFallback() error {
+	visited := map[*OCR]bool{o: true}
+	for f := o.Fallback; f != nil; f = f.Fallback {
+		if visited[f] {
+			return errFallbackCycle
+		}
+		visited[f] = true
+	}
+	return nil
+}
+
+// fallbackOnly checks if the text is to be recognized by the Fallback alone, as there are no
+// symbols to search for
+func (o *OCR) fallbackOnly(symbols []*FontSymbol) bool {
+	return len(symbols) == 0 && o.Fallback != nil
+}
+
+// arrangeWithFallback writes the text of the symbols detected inside rect, like arrange, but
+// using the Fallback, if set, like recognizeWithFallback
+func (o *OCR) arrangeWithFallback(ctx context.Context, bi *imageBinary, rect image.Rectangle, all []*fontSymbolLookup) (string, error) {
+	if o.Fallback != nil {
+		return o.recognizeWithFallback(ctx, bi, rect, all)
+	}
+	return o.arrange(bi, all), nil
+}
+
+// recognizeWithFallback writes the text of the symbols found, recognizing again with the
+// Fallback OCR the lines with a confidence below FallbackConfidence
+func (o *OCR) recognizeWithFallback(ctx context.Context, bi *imageBinary, rect image.Rectangle, all []*fontSymbolLookup) (string, error) {
+	if err := o.checkFallback(); err != nil {
+		return "", err
+	}
+	if len(all) == 0 {
+		return o.Fallback.recognize(ctx, bi, rect, o.Fallback.allSymbols)
+	}
+	if len(all) < o.MinMatches {
+		return "", nil
+	}
+
+	lines, err := o.fallbackLines(ctx, bi, rect, all)
+	if err != nil {
+		return "", err
+	}
+	text := make([]string, len(lines))
+	for i, line := range lines {
+		text[i] = line.ocr.render(bi, line.symbols)
+	}
+	return strings.Join(text, "\n"), nil
+}
+
+// layoutWithFallback works like recognizeWithFallback, but returning the glyphs of the text
+func (o *OCR) layoutWithFallback(ctx context.Context, bi *imageBinary, rect image.Rectangle, all []*fontSymbolLookup) ([]PlacedGlyph, error) {
+	if err := o.checkFallback(); err != nil {
+		return nil, err
+	}
+	if len(all) == 0 {
+		return o.Fallback.recognizeLayout(ctx, bi, rect, o.Fallback.allSymbols)
+	}
+	if len(all) < o.MinMatches {
+		return nil, nil
+	}
+
+	lines, err := o.fallbackLines(ctx, bi, rect, all)
+	if err != nil {
+		return nil, err
+	}
+	var glyphs []PlacedGlyph
+	for i, line := range lines {
+		placed := line.ocr.placedGlyphs(bi, line.symbols)
+		if i > 0 {
+			// the lines are joined by a single line break, like recognizeWithFallback does
+			placed[0].NewLine, placed[0].BlankLines = true, 0
+		}
+		glyphs = append(glyphs, placed...)
+	}
+	return glyphs, nil
+}
+
+// fallbackLine is a line of text, with the OCR that recognized its symbols
+type fallbackLine struct {
+	ocr     *OCR
+	symbols []*fontSymbolLookup
+}
+
+// fallbackLines splits the symbols found, sorted in reading order, in lines, replacing the ones
+// with a confidence below FallbackConfidence with the symbols the Fallback OCR recognizes there
+func (o *OCR) fallbackLines(ctx context.Context, bi *imageBinary, rect image.Rectangle, all []*fontSymbolLookup) ([]fallbackLine, error) {
+	tallest := 0
+	for _, s := range o.Fallback.allSymbols {
+		tallest = max(tallest, s.height)
+	}
+
+	var lines []fallbackLine
+	for _, line := range o.lines(bi, all) {
+		if o.confidence(line) >= o.FallbackConfidence {
+			lines = append(lines, fallbackLine{o, line})
+			continue
+		}
+
+		// scan a band tall enough for the symbols of the fallback, centered on the line, and
+		// keep the symbols centered inside the line
+		r := lineRect(line)
+		extra := max(tallest-r.Dy(), 0)/2 + 1
+		band := image.Rect(rect.Min.X, max(r.Min.Y-extra, rect.Min.Y), rect.Max.X, min(r.Max.Y-1+extra, rect.Max.Y))
+		found, err := o.Fallback.find(ctx, bi, band, o.Fallback.allSymbols, false)
+		if err != nil {
+			return nil, err
+		}
+		var kept []*fontSymbolLookup
+		for _, s := range o.Fallback.filter(found) {
+			if center := s.y + s.fs.height/2; center >= r.Min.Y && center < r.Max.Y {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) == 0 {
+			lines = append(lines, fallbackLine{o, line})
+			continue
+		}
+		lines = append(lines, fallbackLine{o.Fallback, kept})
+	}
+	return lines, nil
+}
@@ -0,0 +1,138 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// GridCell is the symbol recognized in a cell of a grid.
+type GridCell struct {
+	// The symbol recognized, or empty if none was found in the cell
+	Symbol string
+	// The similarity score of the symbol, ranging from -1 to 1. Zero if none was found
+	G float64
+}
+
+// RecognizeGrid splits the image in a grid of evenly sized cells, like the fields of a form, and
+// recognizes the text of each of them, indexed by row and column. The image is prepared once for
+// all cells. When the size of the image is not a multiple of the number of rows or columns, some
+// cells are a pixel bigger than others. Symbols crossing the boundary between cells are not found.
+func (o *OCR) RecognizeGrid(img image.Image, rows, cols int) ([][]string, error) {
+	cells, err := gridCells(img.Bounds(), rows, cols)
+	if err != nil {
+		return nil, err
+	}
+
+	bi, err := o.prepare(img)
+	if err != nil {
+		return nil, err
+	}
+	grid := make([][]string, rows)
+	for r := range grid {
+		grid[r] = make([]string, cols)
+		for c := range grid[r] {
+			if grid[r][c], err = o.recognize(context.Background(), bi, cells[r][c], o.allSymbols); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return grid, nil
+}
+
+// RecognizeGridDetailed splits the image in a grid of evenly sized cells, and recognizes the
+// single symbol expected in each of them, like in grid CAPTCHAs. Returns the best symbol of each
+// cell, with its score, indexed by row and column. Use the scores to decide which cells to trust.
+func (o *OCR) RecognizeGridDetailed(img image.Image, rows, cols int) ([][]GridCell, error) {
+	cells, err := gridCells(img.Bounds(), rows, cols)
+	if err != nil {
+		return nil, err
+	}
+
+	bi, err := o.prepare(img)
+	if err != nil {
+		return nil, err
+	}
+	grid := make([][]GridCell, rows)
+	for r := range grid {
+		grid[r] = make([]GridCell, cols)
+		for c := range grid[r] {
+			found, err := o.find(context.Background(), bi, cells[r][c], o.allSymbols, false)
+			if err != nil {
+				return nil, err
+			}
+			// the best scoring symbol, breaking ties by position and symbol to be deterministic
+			var best *fontSymbolLookup
+			for _, l := range found {
+				if best == nil || l.g > best.g || l.g == best.g && l.precedes(best) {
+					best = l
+				}
+			}
+			if best != nil {
+				grid[r][c] = GridCell{Symbol: best.fs.symbol, G: best.g}
+			}
+		}
+	}
+	return grid, nil
+}
+
+// gridCells splits the bounds in a grid of evenly sized cells, returning the area to be scanned
+// in the imageBinary for each cell, indexed by row and column
+func gridCells(bounds image.Rectangle, rows, cols int) ([][]image.Rectangle, error) {
+	if rows <= 0 || cols <= 0 || rows > bounds.Dy() || cols > bounds.Dx() {
+		return nil, fmt.Errorf("invalid grid of %dx%d cells for an image of size %v", rows, cols, bounds.Size())
+	}
+	w, h := bounds.Dx(), bounds.Dy()
+	cells := make([][]image.Rectangle, rows)
+	for r := range cells {
+		cells[r] = make([]image.Rectangle, cols)
+		for c := range cells[r] {
+			cells[r][c] = image.Rect(c*w/cols, r*h/rows, (c+1)*w/cols-1, (r+1)*h/rows-1)
+		}
+	}
+	return cells, nil
+}
+
+// RecognizeColumns recognizes the text of an image laid out in a fixed number of columns of the
+// same width, like a capture of a terminal with a monospaced font. Each symbol is placed in the
+// column its center falls in, instead of relying on the gaps between symbols for the spaces, and
+// every line of text is returned as a row of cols cells, with the empty ones filled with spaces.
+// When two symbols fall in the same column, the best scoring one is kept. Each cell holds the
+// text of its symbol, so a row is cols characters long only as long as that text is a single
+// rune: symbols expanded by ExpandLigatures, or prefixed by DebugFamilyPrefix, widen it. Only the
+// rows with text are returned.
+func (o *OCR) RecognizeColumns(img image.Image, cols int) ([]string, error) {
+	if cols <= 0 || cols > img.Bounds().Dx() {
+		return nil, fmt.Errorf("invalid number of columns %d for an image of width %d", cols, img.Bounds().Dx())
+	}
+
+	bi, err := o.prepare(img)
+	if err != nil {
+		return nil, err
+	}
+	all, err := o.detect(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []string
+	for _, line := range o.lines(bi, all) {
+		kept := make([]*fontSymbolLookup, cols)
+		for _, s := range line {
+			c := (s.x + s.fs.width/2) * cols / bi.width
+			if kept[c] == nil || s.g > kept[c].g || s.g == kept[c].g && s.precedes(kept[c]) {
+				kept[c] = s
+			}
+		}
+		cells := make([]string, cols)
+		for i, s := range kept {
+			cells[i] = " "
+			if s != nil {
+				cells[i] = o.text(s.fs)
+			}
+		}
+		rows = append(rows, strings.Join(cells, ""))
+	}
+	return rows, nil
+}
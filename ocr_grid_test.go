@@ -0,0 +1,136 @@
+package lookup
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// drawGrid draws the symbols in a grid of cells of the given size, each symbol at the center of
+// its cell. Empty strings leave the cell blank
+func drawGrid(symbols []*FontSymbol, cells [][]string, width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width*len(cells[0]), height*len(cells)))
+	for r, row := range cells {
+		for c, label := range row {
+			for _, s := range symbols {
+				if s.symbol == label {
+					at := image.Pt(c*width+(width-s.width)/2, r*height+(height-s.height)/2)
+					draw.Draw(img, image.Rectangle{Min: at, Max: at.Add(image.Pt(s.width, s.height))}, s.image.gray(), image.Point{}, draw.Src)
+					break
+				}
+			}
+		}
+	}
+	return img
+}
+
+func TestOCRRecognizeGrid(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It recognizes the text of each cell", func() {
+			grid, err := ocr.RecognizeGrid(loadImageColor("testdata/test3.png"), 2, 1)
+			So(err, ShouldBeNil)
+			So(grid, ShouldResemble, [][]string{{"3662"}, {"3 2€/€"}})
+		})
+
+		Convey("It recognizes cells of different sizes when the image is not evenly divisible", func() {
+			cells := drawGrid(ocr.allSymbols, [][]string{{"3", "6", "2"}, {"€", "", "9"}}, 16, 20)
+			img := image.NewGray(image.Rect(0, 0, 50, 41))
+			draw.Draw(img, cells.Bounds(), cells, image.Point{}, draw.Src)
+			grid, err := ocr.RecognizeGrid(img, 2, 3)
+			So(err, ShouldBeNil)
+			So(grid, ShouldResemble, [][]string{{"3", "6", "2"}, {"€", "", "9"}})
+		})
+
+		Convey("It fails with an invalid grid", func() {
+			_, err := ocr.RecognizeGrid(loadImageColor("testdata/test3.png"), 2, 0)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestOCRRecognizeGridDetailed(t *testing.T) {
+	Convey("Given an image with a symbol in each cell of a grid", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := drawGrid(ocr.allSymbols, [][]string{{"3", "6", "2"}, {"€", "", "9"}}, 16, 20)
+
+		Convey("It recognizes the symbol of each cell, with its score", func() {
+			grid, err := ocr.RecognizeGridDetailed(img, 2, 3)
+			So(err, ShouldBeNil)
+			So(grid, ShouldHaveLength, 2)
+			expected := [][]string{{"3", "6", "2"}, {"€", "", "9"}}
+			for r, row := range expected {
+				So(grid[r], ShouldHaveLength, 3)
+				for c, symbol := range row {
+					So(grid[r][c].Symbol, ShouldEqual, symbol)
+					if symbol == "" {
+						So(grid[r][c].G, ShouldEqual, 0)
+					} else {
+						So(grid[r][c].G, ShouldAlmostEqual, 1)
+					}
+				}
+			}
+		})
+
+		Convey("It keeps the best scoring symbol of a cell, not the biggest one", func() {
+			// the whole first cell, one pixel off, so it scores a bit less than the "3" it contains
+			cell := image.NewGray(image.Rect(0, 0, 16, 20))
+			draw.Draw(cell, cell.Bounds(), img, image.Point{}, draw.Src)
+			cell.Pix[0] = 128
+			ocr.AddSymbols(NewFontSymbol("B", cell))
+
+			grid, err := ocr.RecognizeGridDetailed(img, 2, 3)
+			So(err, ShouldBeNil)
+			So(grid[0][0].Symbol, ShouldEqual, "3")
+			So(grid[0][0].G, ShouldAlmostEqual, 1)
+		})
+
+		Convey("It fails with an invalid grid", func() {
+			_, err := ocr.RecognizeGridDetailed(img, 0, 3)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestOCRRecognizeColumns(t *testing.T) {
+	Convey("Given an image with symbols laid out in columns", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := drawGrid(ocr.allSymbols, [][]string{{"3", "", "", "6", "2", ""}, {"", "", "9", "", "", "€"}}, 12, 20)
+
+		Convey("It places each symbol in its column", func() {
+			rows, err := ocr.RecognizeColumns(img, 6)
+			So(err, ShouldBeNil)
+			So(rows, ShouldResemble, []string{"3  62 ", "  9  €"})
+		})
+
+		Convey("It keeps the best scoring symbol of a column shared by two", func() {
+			img := drawGrid(ocr.allSymbols, [][]string{{"2", "6"}}, 12, 20)
+			// a stray pixel in the "6" makes it score less than the "2"
+			img.SetGray(12+6, 10, color.Gray{Y: 128})
+			rows, err := ocr.RecognizeColumns(img, 1)
+			So(err, ShouldBeNil)
+			So(rows, ShouldResemble, []string{"2"})
+		})
+
+		Convey("It widens the rows with the symbols expanded to more than one rune", func() {
+			ocr.ExpandLigatures = map[string]string{"€": "EUR"}
+			rows, err := ocr.RecognizeColumns(img, 6)
+			So(err, ShouldBeNil)
+			So(rows, ShouldResemble, []string{"3  62 ", "  9  EUR"})
+		})
+
+		Convey("It returns an error for an invalid number of columns", func() {
+			_, err := ocr.RecognizeColumns(img, 0)
+			So(err, ShouldNotBeNil)
+			_, err = ocr.RecognizeColumns(img, 73)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,91 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"image"
+	"math"
+	"strings"
+)
+
+// hocrHeader starts an hOCR document, declaring the elements it uses
+const hocrHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=utf-8" />
+<meta name="ocr-system" content="lookup" />
+<meta name="ocr-capabilities" content="ocr_page ocr_line ocrx_word ocrx_cchar" />
+</head>
+<body>
+`
+
+// RecognizeHOCR recognizes the text in the image and returns it as an hOCR document, to be used
+// with tools that overlay the text on the image. Each line of text is an ocr_line, the words
+// (symbols not separated by spaces) of a line are ocrx_words, and each symbol is an ocrx_cchar,
+// all of them with their bounding boxes in the coordinates of the image. Words have their
+// confidence (x_wconf) and symbols their score (x_confs), in percent.
+func (o *OCR) RecognizeHOCR(img image.Image) (string, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+	all, err := o.detect(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols, false)
+	if err != nil {
+		return "", err
+	}
+	if len(all) < o.MinMatches {
+		all = nil
+	}
+
+	var str strings.Builder
+	str.WriteString(hocrHeader)
+	fmt.Fprintf(&str, "<div class='ocr_page' id='page_1' title='bbox %s'>\n", hocrBBox(img.Bounds()))
+
+	// split the lines in words, using the same spaces and line breaks as the text
+	var lines [][][]*fontSymbolLookup
+	for i, p := range o.layout(bi, all) {
+		if i == 0 || p.newLine {
+			lines = append(lines, nil)
+		}
+		line := &lines[len(lines)-1]
+		if len(*line) == 0 || p.spaces > 0 || p.unknown {
+			*line = append(*line, nil)
+		}
+		(*line)[len(*line)-1] = append((*line)[len(*line)-1], p.fontSymbolLookup)
+	}
+
+	word := 0
+	for l, line := range lines {
+		var symbols []*fontSymbolLookup
+		for _, w := range line {
+			symbols = append(symbols, w...)
+		}
+		fmt.Fprintf(&str, " <span class='ocr_line' id='line_1_%d' title='bbox %s'>\n", l+1, hocrBBox(lineRect(symbols).Add(bi.offset)))
+		for _, w := range line {
+			word++
+			fmt.Fprintf(&str, "  <span class='ocrx_word' id='word_1_%d' title='bbox %s; x_wconf %d'>",
+				word, hocrBBox(lineRect(w).Add(bi.offset)), hocrConfidence(o.confidence(w)))
+			for _, s := range w {
+				fmt.Fprintf(&str, "<span class='ocrx_cchar' title='bbox %s; x_confs %d'>%s</span>",
+					hocrBBox(lineRect([]*fontSymbolLookup{s}).Add(bi.offset)), hocrConfidence(s.g), html.EscapeString(o.text(s.fs)))
+			}
+			str.WriteString("</span>\n")
+		}
+		str.WriteString(" </span>\n")
+	}
+
+	str.WriteString("</div>\n</body>\n</html>\n")
+	return str.String(), nil
+}
+
+// hocrBBox formats a rectangle as the coordinates of an hOCR bbox property
+func hocrBBox(r image.Rectangle) string {
+	return fmt.Sprintf("%d %d %d %d", r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+}
+
+// hocrConfidence converts a score to a confidence in percent, as used by hOCR
+func hocrConfidence(g float64) int {
+	return int(math.Round(math.Max(0, math.Min(g, 1)) * 100))
+}
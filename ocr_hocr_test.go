@@ -0,0 +1,61 @@
+package lookup
+
+import (
+	"encoding/xml"
+	_ "image/png"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOCRRecognizeHOCR(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("When I recognize an image as hOCR", func() {
+			doc, err := ocr.RecognizeHOCR(loadImageColor("testdata/test3.png"))
+			So(err, ShouldBeNil)
+
+			Convey("It is well formed", func() {
+				d := xml.NewDecoder(strings.NewReader(doc))
+				d.Strict = false
+				var err error
+				for err == nil {
+					_, err = d.Token()
+				}
+				So(err.Error(), ShouldEqual, "EOF")
+			})
+
+			Convey("It has the bounds of the image in the page", func() {
+				So(doc, ShouldContainSubstring, "<div class='ocr_page' id='page_1' title='bbox 0 0 84 50'>")
+			})
+
+			Convey("It has a line for each line of text", func() {
+				So(strings.Count(doc, "class='ocr_line'"), ShouldEqual, 2)
+				So(doc, ShouldContainSubstring, "<span class='ocr_line' id='line_1_1' title='bbox 6 4 47 18'>")
+				So(doc, ShouldContainSubstring, "<span class='ocr_line' id='line_1_2' title='bbox 12 25 79 41'>")
+			})
+
+			Convey("It splits the lines in words, with a character for each symbol", func() {
+				So(strings.Count(doc, "class='ocrx_word'"), ShouldEqual, 3)
+				So(strings.Count(doc, "class='ocrx_cchar'"), ShouldEqual, 9)
+				So(doc, ShouldContainSubstring, "<span class='ocrx_word' id='word_1_2' title='bbox 12 27 21 41; x_wconf 100'><span class='ocrx_cchar' title='bbox 12 27 21 41; x_confs 100'>3</span></span>")
+			})
+
+			Convey("It has the confidence of each symbol", func() {
+				So(doc, ShouldContainSubstring, "<span class='ocrx_cchar' title='bbox 15 4 25 18; x_confs 88'>6</span>")
+			})
+		})
+
+		Convey("When a symbol has characters with a special meaning in HTML", func() {
+			ocr.ExpandLigatures = map[string]string{"€": "<&>"}
+			doc, _ := ocr.RecognizeHOCR(loadImageColor("testdata/test3.png"))
+
+			Convey("It escapes them", func() {
+				So(doc, ShouldContainSubstring, "&lt;&amp;&gt;</span>")
+			})
+		})
+	})
+}
@@ -0,0 +1,383 @@
+package lookup
+
+import (
+	"context"
+	"image"
+	"math"
+	"sort"
+	"strings"
+)
+
+// placedSymbol is a recognized symbol, with what separates it from the previous one in the text
+type placedSymbol struct {
+	*fontSymbolLookup
+	// number of spaces before the symbol
+	spaces int
+	// whether the symbol starts a new line
+	newLine bool
+	// number of empty lines before the line the symbol starts
+	blankLines int
+	// whether there is ink not matched by any symbol before the symbol
+	unknown bool
+}
+
+// layout decides how the symbols, sorted in reading order, are separated in the recognized text
+func (o *OCR) layout(bi *imageBinary, all []*fontSymbolLookup) []placedSymbol {
+	placed := make([]placedSymbol, len(all))
+	if len(all) == 0 {
+		return placed
+	}
+
+	var lineOf map[*fontSymbolLookup]int
+	var segmented [][]*fontSymbolLookup
+	if o.LineSegmentation {
+		segmented = o.segmentLines(all)
+		lineOf = make(map[*fontSymbolLookup]int, len(all))
+		for n, line := range segmented {
+			for _, s := range line {
+				lineOf[s] = n
+			}
+		}
+	}
+
+	x := all[0].x
+	previousAdvance := 0
+	lineStart := 0
+	for i, s := range all {
+		p := placedSymbol{fontSymbolLookup: s}
+
+		// if distance between end of previous symbol and beginning of the
+		// current is larger then a char size, then it is a space
+		// This should not be applied in the beginning (i == 0) as it would put a white space for
+		// any s.x > maxCX will have a (useless) whitespace in front
+		maxCurrentPreviousAdvance := max(previousAdvance, s.fs.Advance())
+		switch {
+		case i == 0:
+			p.spaces = o.indentation(s)
+		case o.LineSegmentation && lineOf[s] != lineOf[all[i-1]]:
+			p.newLine = true
+			p.spaces = o.indentation(s)
+			if n := lineOf[s]; n > 0 {
+				p.blankLines = blankLinesBetween(segmented[n-1], segmented[n])
+			}
+		case !o.LineSegmentation && s.x < x:
+			// if we drop back, then we have an end of line
+			p.newLine = true
+			p.spaces = o.indentation(s)
+		case float64(s.x-x) >= o.spaceFactor()*float64(maxCurrentPreviousAdvance)-float64(o.SpaceTolerance):
+			p.spaces = 1
+			if o.ProportionalSpaces {
+				p.spaces = max(int(math.Round(float64(s.x-x)/float64(maxCurrentPreviousAdvance))), 1)
+			}
+			if o.lineTooLong(all[lineStart:i], s) {
+				p.spaces = 0
+				p.newLine = true
+			}
+		}
+		if i > 0 && !p.newLine && o.UnknownGlyph != "" && hasInkBetween(bi, all[i-1], s) {
+			// the gap may be wide enough for spaces too, written after the glyph
+			p.unknown = true
+		}
+		if p.newLine {
+			lineStart = i
+		}
+
+		x = s.x + s.fs.Advance()
+		previousAdvance = s.fs.Advance()
+		placed[i] = p
+	}
+	reverseRTLRuns(placed)
+	return placed
+}
+
+// segmentLines groups the symbols in lines, made of the symbols vertically overlapping each other,
+// like yCross does. Lines are sorted top to bottom, and their symbols left to right
+func (o *OCR) segmentLines(all []*fontSymbolLookup) [][]*fontSymbolLookup {
+	sorted := append([]*fontSymbolLookup(nil), all...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i].bounds(), sorted[j].bounds()
+		if a.Min.Y != b.Min.Y {
+			return a.Min.Y < b.Min.Y
+		}
+		return sorted[i].precedes(sorted[j])
+	})
+
+	var lines [][]*fontSymbolLookup
+	var spans []image.Rectangle
+	for _, s := range sorted {
+		r := s.bounds()
+		n := -1
+		for i, span := range spans {
+			if r.Min.Y <= span.Max.Y && r.Max.Y >= span.Min.Y {
+				n = i
+				break
+			}
+		}
+		if n < 0 {
+			lines, spans = append(lines, nil), append(spans, r)
+			n = len(lines) - 1
+		}
+		lines[n] = append(lines[n], s)
+		spans[n] = spans[n].Union(r)
+	}
+
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool {
+			return line[i].x+line[i].fs.originX < line[j].x+line[j].fs.originX
+		})
+	}
+	return lines
+}
+
+// blankLinesBetween is the number of empty lines that fit in the gap between two lines of symbols,
+// taking the height of their tallest symbol as the height of a line
+func blankLinesBetween(prev, next []*fontSymbolLookup) int {
+	bottom, top, height := math.MinInt, math.MaxInt, 0
+	for _, s := range prev {
+		bottom = max(bottom, s.bounds().Max.Y)
+		height = max(height, s.bounds().Dy())
+	}
+	for _, s := range next {
+		top = min(top, s.bounds().Min.Y)
+		height = max(height, s.bounds().Dy())
+	}
+	if height == 0 || top <= bottom {
+		return 0
+	}
+	return (top - bottom) / height
+}
+
+// reverseRTLRuns reverses, in place, each run of adjacent right-to-left symbols of a line, so they
+// are written in logical order. What separates the symbols stays where it was, so the spaces
+// between the words of a run are kept, and the run still starts where it started
+func reverseRTLRuns(placed []placedSymbol) {
+	for i := 0; i < len(placed); i++ {
+		if !placed[i].fs.rtl {
+			continue
+		}
+		j := i
+		for j+1 < len(placed) && placed[j+1].fs.rtl && !placed[j+1].newLine {
+			j++
+		}
+		for a, b := i, j; a < b; a, b = a+1, b-1 {
+			placed[a].fontSymbolLookup, placed[b].fontSymbolLookup = placed[b].fontSymbolLookup, placed[a].fontSymbolLookup
+		}
+		// the separators in between are now read from the end of the run to its start
+		for a, b := i+1, j; a < b; a, b = a+1, b-1 {
+			placed[a].spaces, placed[b].spaces = placed[b].spaces, placed[a].spaces
+			placed[a].unknown, placed[b].unknown = placed[b].unknown, placed[a].unknown
+		}
+		i = j
+	}
+}
+
+// spaceFactor is the SpaceFactor, taking zero as the default of 1
+func (o *OCR) spaceFactor() float64 {
+	if o.SpaceFactor == 0 {
+		return 1
+	}
+	return o.SpaceFactor
+}
+
+// Lines returns the area of each line of text found in the image, in reading order, without
+// building the text itself. Useful for layout analysis, like counting lines or measuring the
+// spacing between them
+func (o *OCR) Lines(img image.Image) ([]image.Rectangle, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return nil, err
+	}
+	all, err := o.detect(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []image.Rectangle
+	for _, line := range o.lines(bi, all) {
+		lines = append(lines, lineRect(line).Add(bi.offset))
+	}
+	return lines, nil
+}
+
+// PlacedGlyph is a symbol recognized, with what separates it from the previous one in the text.
+type PlacedGlyph struct {
+	Match
+	// What is written in the text for the symbol, usually the symbol itself
+	Text string
+	// The number of spaces written before the symbol
+	Spaces int
+	// Whether the symbol starts a new line, after the first one
+	NewLine bool
+	// The number of empty lines written before the line the symbol starts
+	BlankLines int
+	// Whether the UnknownGlyph is written before the symbol
+	Unknown bool
+}
+
+// RecognizeLayout recognizes the symbols in the image, returning them in the order their text is
+// written by Recognize, with what separates each one from the previous. Use it to map each
+// character of the text back to the area of the image it was read from. The lines recognized
+// again by the Fallback have the glyphs it found there. Delimited doesn't change the glyphs: the
+// delimiters it writes are only a way to tell the glyphs apart in the text.
+func (o *OCR) RecognizeLayout(img image.Image) ([]PlacedGlyph, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return nil, err
+	}
+	return o.recognizeLayout(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols)
+}
+
+// recognizeLayout returns the glyphs of the text of the symbols detected inside rect, like
+// recognize writes it
+func (o *OCR) recognizeLayout(ctx context.Context, bi *imageBinary, rect image.Rectangle, symbols []*FontSymbol) ([]PlacedGlyph, error) {
+	if o.fallbackOnly(symbols) {
+		return o.layoutWithFallback(ctx, bi, rect, nil)
+	}
+	all, err := o.detect(ctx, bi, rect, symbols, false)
+	if err != nil {
+		return nil, err
+	}
+	if o.Fallback != nil {
+		return o.layoutWithFallback(ctx, bi, rect, all)
+	}
+	if len(all) < o.MinMatches {
+		return nil, nil
+	}
+	return o.placedGlyphs(bi, all), nil
+}
+
+// placedGlyphs lays out the symbols, sorted in reading order, returning them as glyphs
+func (o *OCR) placedGlyphs(bi *imageBinary, all []*fontSymbolLookup) []PlacedGlyph {
+	var glyphs []PlacedGlyph
+	for _, p := range o.layout(bi, all) {
+		glyphs = append(glyphs, PlacedGlyph{
+			Match:      p.match(bi.offset),
+			Text:       o.text(p.fs),
+			Spaces:     p.spaces,
+			NewLine:    p.newLine,
+			BlankLines: p.blankLines,
+			Unknown:    p.unknown,
+		})
+	}
+	return glyphs
+}
+
+// lines splits the symbols, sorted in reading order, in the lines of text they are laid out in
+func (o *OCR) lines(bi *imageBinary, all []*fontSymbolLookup) [][]*fontSymbolLookup {
+	var lines [][]*fontSymbolLookup
+	for i, p := range o.layout(bi, all) {
+		if i == 0 || p.newLine {
+			lines = append(lines, nil)
+		}
+		lines[len(lines)-1] = append(lines[len(lines)-1], all[i])
+	}
+	return lines
+}
+
+// lineRect is the area covered by the symbols of a line, in the coordinates of the imageBinary
+func lineRect(line []*fontSymbolLookup) image.Rectangle {
+	var r image.Rectangle
+	for _, s := range line {
+		r = r.Union(image.Rect(s.x, s.y, s.x+s.fs.width, s.y+s.fs.height))
+	}
+	return r
+}
+
+// renderDelimited writes the symbols, sorted in reading order, enclosing each one in "|"
+func (o *OCR) renderDelimited(bi *imageBinary, all []*fontSymbolLookup) string {
+	var str strings.Builder
+	for i, p := range o.layout(bi, all) {
+		if p.newLine {
+			str.WriteString(strings.Repeat("\n", p.blankLines+1))
+		}
+		if i == 0 || p.newLine {
+			str.WriteString("|")
+		}
+		if p.unknown {
+			str.WriteString(delimiterEscaper.Replace(o.UnknownGlyph) + "|")
+		}
+		if p.spaces > 0 {
+			str.WriteString(strings.Repeat(" ", p.spaces) + "|")
+		}
+		str.WriteString(delimiterEscaper.Replace(o.text(p.fs)) + "|")
+	}
+	return str.String()
+}
+
+// indentation is the number of spaces written before the first symbol of a line, as many
+// advances of the symbol as fit between the left edge of the area recognized and it, when
+// PreserveLeadingSpace is set
+func (o *OCR) indentation(s *fontSymbolLookup) int {
+	advance := s.fs.Advance()
+	if !o.PreserveLeadingSpace || advance <= 0 {
+		return 0
+	}
+	return max(s.x-s.left, 0) / advance
+}
+
+// lineTooLong checks if a line would exceed the maximum line size if next is added to it
+func (o *OCR) lineTooLong(line []*fontSymbolLookup, next *fontSymbolLookup) bool {
+	if o.MaxLineSymbols > 0 && len(line) >= o.MaxLineSymbols {
+		return true
+	}
+	return o.MaxLineWidth > 0 && next.x+next.fs.width-line[0].x > o.MaxLineWidth
+}
+
+func (o *OCR) arrange(bi *imageBinary, all []*fontSymbolLookup) string {
+	if len(all) < o.MinMatches {
+		return ""
+	}
+	return o.render(bi, all)
+}
+
+// delimiterEscaper escapes the delimiters in the text of the symbols of delimited output
+var delimiterEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+
+// render writes the symbols, sorted in reading order, as text
+func (o *OCR) render(bi *imageBinary, all []*fontSymbolLookup) string {
+	if o.Delimited {
+		return o.renderDelimited(bi, all)
+	}
+	var str strings.Builder
+	for i, p := range o.layout(bi, all) {
+		switch {
+		case p.newLine:
+			str.WriteString(strings.Repeat("\n", p.blankLines+1))
+		case p.unknown:
+			str.WriteString(o.UnknownGlyph)
+		case o.DebugFamilyPrefix && i > 0 && p.spaces == 0:
+			// keep the prefixed symbols apart
+			str.WriteString(" ")
+		}
+		str.WriteString(strings.Repeat(" ", p.spaces))
+		str.WriteString(o.text(p.fs))
+	}
+	return str.String()
+}
+
+// text returns what should be written in the recognized text for the symbol
+func (o *OCR) text(fs *FontSymbol) string {
+	text := fs.symbol
+	if expanded, ok := o.ExpandLigatures[fs.symbol]; ok {
+		text = expanded
+	}
+	if o.DebugFamilyPrefix {
+		text = fs.family + ":" + text
+	}
+	return text
+}
+
+// minInkGap is the minimum width, in pixels, of a gap between two symbols to be checked for ink
+const minInkGap = 2
+
+// hasInkBetween checks if the gap between two consecutive symbols of the same line contains
+// anything other than background
+func hasInkBetween(bi *imageBinary, prev, next *fontSymbolLookup) bool {
+	x1, x2 := prev.x+prev.fs.width, next.x-1
+	if x2-x1+1 < minInkGap {
+		return false
+	}
+	y1 := min(prev.y, next.y)
+	y2 := max(prev.y+prev.fs.height, next.y+next.fs.height) - 1
+	return bi.hasInk(x1, y1, x2, y2)
+}
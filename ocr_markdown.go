@@ -0,0 +1,81 @@
+package lookup
+
+import (
+	"context"
+	"image"
+	"strings"
+)
+
+// markdownEscaper escapes the characters with a special meaning in Markdown
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `#`, `\#`, `<`, `\<`, `>`, `\>`,
+)
+
+// emphasis is the style of a run of symbols in Markdown
+type emphasis struct {
+	bold, italic bool
+}
+
+func (e emphasis) open() string {
+	var s string
+	if e.bold {
+		s += "**"
+	}
+	if e.italic {
+		s += "_"
+	}
+	return s
+}
+
+func (e emphasis) close() string {
+	var s string
+	if e.italic {
+		s += "_"
+	}
+	if e.bold {
+		s += "**"
+	}
+	return s
+}
+
+// RecognizeMarkdown recognizes the text in the image and formats it as Markdown. Runs of bold
+// symbols (with FontWeightBold) are wrapped in "**", runs of italic symbols in "_", and each line
+// of text becomes a paragraph. Characters with a special meaning in Markdown are escaped.
+func (o *OCR) RecognizeMarkdown(img image.Image) (string, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+	all, err := o.detect(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols, false)
+	if err != nil {
+		return "", err
+	}
+	if len(all) < o.MinMatches {
+		return "", nil
+	}
+
+	var str strings.Builder
+	var current emphasis
+	for _, p := range o.layout(bi, all) {
+		e := emphasis{bold: p.fs.weight == FontWeightBold, italic: p.fs.italic}
+		changed := p.newLine || e != current
+		if changed {
+			// markers are closed before any space, as Markdown doesn't allow spaces inside them
+			str.WriteString(current.close())
+		}
+		switch {
+		case p.newLine:
+			str.WriteString("\n\n")
+		case p.unknown:
+			str.WriteString(markdownEscaper.Replace(o.UnknownGlyph))
+		}
+		str.WriteString(strings.Repeat(" ", p.spaces))
+		if changed {
+			str.WriteString(e.open())
+			current = e
+		}
+		str.WriteString(markdownEscaper.Replace(o.text(p.fs)))
+	}
+	str.WriteString(current.close())
+	return str.String(), nil
+}
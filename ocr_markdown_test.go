@@ -0,0 +1,36 @@
+package lookup
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOCRRecognizeMarkdown(t *testing.T) {
+	Convey("Given an OCR with bold and italic symbols", t, func() {
+		ocr := NewOCR(0.8)
+		symbols, _ := loadFont("testdata/font_1")
+		for _, s := range symbols {
+			switch s.symbol {
+			case "2":
+				s.weight = FontWeightBold
+			case "€":
+				s.italic = true
+			}
+		}
+		ocr.AddFontFamily("font_1", symbols...)
+
+		Convey("It wraps the runs of each style in Markdown emphasis", func() {
+			text, err := ocr.RecognizeMarkdown(loadImageColor("testdata/test3.png"))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "366**2**\n\n3 **2**_€_/_€_")
+		})
+
+		Convey("It escapes characters with a special meaning", func() {
+			ocr.ExpandLigatures = map[string]string{"/": "*"}
+			text, err := ocr.RecognizeMarkdown(loadImageColor("testdata/test3.png"))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "366**2**\n\n3 **2**_€_\\*_€_")
+		})
+	})
+}
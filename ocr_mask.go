@@ -0,0 +1,53 @@
+package lookup
+
+import (
+	"context"
+	"image"
+)
+
+// RecognizeMaskImage works like Recognize, but only considers the pixels of the image where the
+// mask, in the same coordinates as the image, is not zero. Symbols are only recognized when they
+// are fully inside the mask, so text can be read from irregular areas, like speech bubbles, that
+// a rectangle can not describe. Pixels outside the bounds of the mask are considered zero.
+func (o *OCR) RecognizeMaskImage(img image.Image, mask *image.Gray) (string, error) {
+	rect, err := scanRect(img.Bounds(), mask.Bounds())
+	if err != nil {
+		return "", err
+	}
+
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", err
+	}
+	found, err := o.find(context.Background(), bi, rect, o.allSymbols, false)
+	if err != nil {
+		return "", err
+	}
+	return o.arrange(bi, o.confidentLines(bi, o.filter(insideMask(found, mask, bi.offset)))), nil
+}
+
+// insideMask keeps only the candidates that do not cover any zero pixel of the mask
+func insideMask(found []*fontSymbolLookup, mask *image.Gray, offset image.Point) []*fontSymbolLookup {
+	inside := found[:0]
+	for _, l := range found {
+		if masked(mask, image.Rect(l.x, l.y, l.x+l.fs.width, l.y+l.fs.height).Add(offset)) {
+			inside = append(inside, l)
+		}
+	}
+	return inside
+}
+
+// masked checks if all pixels of r are set in the mask
+func masked(mask *image.Gray, r image.Rectangle) bool {
+	if !r.In(mask.Bounds()) {
+		return false
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if mask.Pix[mask.PixOffset(x, y)] == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package lookup
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/png"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOCRRecognizeMaskImage(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+		mask := image.NewGray(img.Bounds())
+		set := func(r image.Rectangle) {
+			draw.Draw(mask, r, image.NewUniform(color.Gray{Y: 255}), image.Point{}, draw.Src)
+		}
+
+		Convey("When the mask covers the first line and the start of the second one", func() {
+			set(image.Rect(0, 0, 84, 20))
+			set(image.Rect(0, 20, 24, 50))
+			text, err := ocr.RecognizeMaskImage(img, mask)
+
+			Convey("It only recognizes the text inside the mask", func() {
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3")
+			})
+		})
+
+		Convey("When the mask only covers part of a symbol", func() {
+			set(image.Rect(0, 0, 84, 12))
+			text, _ := ocr.RecognizeMaskImage(img, mask)
+
+			Convey("It does not recognize it", func() {
+				So(text, ShouldEqual, "")
+			})
+		})
+
+		Convey("When the mask is outside the image", func() {
+			_, err := ocr.RecognizeMaskImage(img, image.NewGray(image.Rect(100, 100, 120, 120)))
+
+			Convey("It returns an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
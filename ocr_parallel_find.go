@@ -1,28 +1,73 @@
 package lookup
 
 import (
+	"context"
+	"fmt"
 	"image"
 	"sync"
+	"time"
 )
 
-// Search for all symbols in the image in parallel. Uses a Fan-out/fan-in approach.
-func findAllInParallel(numWorkers int, symbols []*FontSymbol, img *imageBinary, threshold float64, rect image.Rectangle) ([]*fontSymbolLookup, error) {
-	f := &parallelFinder{
+// newParallelFinder returns a finder searching for all symbols in the image in parallel. Uses a
+// Fan-out/fan-in approach.
+func newParallelFinder(ctx context.Context, numWorkers int, symbols []*FontSymbol, img *imageBinary, threshold float64, rect image.Rectangle) *parallelFinder {
+	return &parallelFinder{
+		ctx:        ctx,
 		numWorkers: max(numWorkers, 1),
 		symbols:    symbols,
 		img:        img,
 		threshold:  threshold,
 		rect:       rect,
 	}
-	return f.lookupAll()
 }
 
 type parallelFinder struct {
+	// ctx cancels the search. The workers check it between symbols, so a cancellation stops
+	// the search once the symbols being searched at the moment are done
+	ctx        context.Context
 	img        *imageBinary
 	threshold  float64
 	numWorkers int
 	symbols    []*FontSymbol
 	rect       image.Rectangle
+
+	// onSymbolDone, if set, is called by the workers (possibly concurrently) after searching
+	// for each symbol (or each band of it), with the time it took
+	onSymbolDone func(symbol *FontSymbol, d time.Duration)
+
+	// symbolThresholds makes the symbols with a MinScore be searched with it, instead of threshold
+	symbolThresholds bool
+
+	// bands, when greater than 1, splits the search of each symbol in that many horizontal bands
+	// of the positions the symbol can be at, so each band can be searched by a different worker
+	bands int
+
+	// matcher, if set, scores the positions instead of the Normalized Cross Correlation, using
+	// the gray scale image rebuilt in imgGray
+	matcher Matcher
+	imgGray *image.Gray
+
+	// certainty, when greater than zero, makes the candidates scoring at least certainty settle
+	// their area, which is not searched any more for other symbols. The tasks are settled in the
+	// order they are searched in, whatever order they finish in: the candidates of a task that
+	// overlap the areas of the ones before are dropped, and the areas of the rest are added to
+	// certain. The tasks finished ahead of their turn wait in pending
+	certainty float64
+	certainMu sync.RWMutex
+	certain   []image.Rectangle
+	pending   map[int]settledTask
+	settled   int
+
+	// partial makes a cancelled search return the candidates found until then, with the error of
+	// the context, instead of none
+	partial bool
+
+	// maxCandidates, when greater than zero, makes the search fail when more candidates are found
+	maxCandidates int
+
+	// expected, when greater than zero, stops the search as soon as candidates were found in
+	// that many places not overlapping each other
+	expected int
 }
 
 type lookupResult struct {
@@ -30,48 +75,216 @@ type lookupResult struct {
 	err error
 }
 
-func (f *parallelFinder) prepare(done <-chan struct{}) <-chan *FontSymbol {
-	out := make(chan *FontSymbol)
+// searchTask is the search of a symbol in a part of the area searched
+type searchTask struct {
+	// index is the position of the task in the order they are searched in
+	index  int
+	symbol *FontSymbol
+	// rect is the area to search, with the bottom-right corner included
+	rect image.Rectangle
+}
+
+// settledTask is the candidates a search task found, once settled
+type settledTask struct {
+	symbol *FontSymbol
+	found  []GPoint
+}
+
+func (f *parallelFinder) prepare(ctx context.Context) <-chan searchTask {
+	out := make(chan searchTask)
 	go func() {
 		defer close(out)
+		index := 0
 		for _, s := range f.symbols {
-			select {
-			case out <- s:
-			case <-done:
-				return
+			for _, r := range f.split(s) {
+				task := searchTask{index, s, r}
+				index++
+				select {
+				case out <- task:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}()
 	return out
 }
 
-func (f *parallelFinder) addWorker(done <-chan struct{}, in <-chan *FontSymbol) <-chan lookupResult {
+// split divides the area searched in bands for the symbol. The positions (top-left corners) the
+// symbol can be at are split in disjoint bands, so each match is found in exactly one band, and
+// the area of a band extends down to fit the symbol at its last positions
+func (f *parallelFinder) split(symbol *FontSymbol) []image.Rectangle {
+	positions := f.rect.Dy() - symbol.height + 2
+	if f.bands <= 1 || positions < 2 {
+		return []image.Rectangle{f.rect}
+	}
+	bands := min(f.bands, positions)
+	rects := make([]image.Rectangle, bands)
+	for b := range rects {
+		y1 := f.rect.Min.Y + b*positions/bands
+		y2 := f.rect.Min.Y + (b+1)*positions/bands - 1
+		rects[b] = image.Rect(f.rect.Min.X, y1, f.rect.Max.X, y2+symbol.height-1)
+	}
+	return rects
+}
+
+// numTasks returns the number of searches the workers do, one for each symbol and band
+func (f *parallelFinder) numTasks() int {
+	n := 0
+	for _, s := range f.symbols {
+		n += len(f.split(s))
+	}
+	return n
+}
+
+func (f *parallelFinder) addWorker(ctx context.Context, in <-chan searchTask) <-chan lookupResult {
 	out := make(chan lookupResult)
 	go func() {
 		defer close(out)
-		for symbol := range in {
-			pp, err := lookupAll(f.img, f.rect.Min.X, f.rect.Min.Y, f.rect.Max.X, f.rect.Max.Y, symbol.image, f.threshold)
+		send := func(r lookupResult) bool {
+			select {
+			case out <- r:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for task := range in {
+			if ctx.Err() != nil {
+				return
+			}
+			symbol, r := task.symbol, task.rect
+			threshold := f.threshold
+			if f.symbolThresholds && symbol.minScore > 0 {
+				threshold = symbol.minScore
+			}
+			start := time.Now()
+			var pp []GPoint
+			var err error
+			if f.certainty > 0 {
+				pp, err = f.lookupUncertain(r, symbol, threshold)
+			} else if f.matcher != nil {
+				pp = matchAll(f.matcher, f.imgGray, r.Min.X, r.Min.Y, r.Max.X, r.Max.Y, symbol.image.gray(), threshold)
+			} else {
+				pp, err = lookupAll(f.img, r.Min.X, r.Min.Y, r.Max.X, r.Max.Y, symbol.image, threshold)
+			}
+			if f.onSymbolDone != nil {
+				f.onSymbolDone(symbol, time.Since(start))
+			}
 			if err != nil {
-				out <- lookupResult{nil, err}
-				continue
+				send(lookupResult{nil, err})
+				return
 			}
-			if pp != nil {
-				for _, p := range pp {
-					l := newFontSymbolLookup(symbol, p.X, p.Y, p.G)
-					select {
-					case out <- lookupResult{l, nil}:
-					case <-done:
+			ready := []settledTask{{symbol, pp}}
+			if f.certainty > 0 {
+				ready = f.settle(task, pp)
+			}
+			for _, t := range ready {
+				for _, p := range t.found {
+					l := newFontSymbolLookup(t.symbol, p.X, p.Y, p.G)
+					l.left = f.rect.Min.X
+					if !send(lookupResult{l, nil}) {
 						return
 					}
 				}
 			}
-
 		}
 	}()
 	return out
 }
 
-func (f *parallelFinder) merge(done chan struct{}, cs []<-chan lookupResult) <-chan lookupResult {
+// lookupUncertain works like lookupAll, but skipping the positions where the symbol would overlap
+// the area of a certain candidate settled so far
+func (f *parallelFinder) lookupUncertain(r image.Rectangle, symbol *FontSymbol, threshold float64) ([]GPoint, error) {
+	var list []GPoint
+	var symbolGray *image.Gray
+	if f.matcher != nil {
+		symbolGray = symbol.image.gray()
+	}
+	for x := r.Min.X; x <= r.Max.X-symbol.width+1; x++ {
+		certain := f.certainAreas()
+		for y := r.Min.Y; y <= r.Max.Y-symbol.height+1; y++ {
+			area := image.Rect(x, y, x+symbol.width, y+symbol.height)
+			if overlapsAnyRect(area, certain) {
+				continue
+			}
+
+			var g *GPoint
+			if f.matcher != nil {
+				if score := f.matcher.Score(symbolGray, f.imgGray, x, y); score >= threshold {
+					g = &GPoint{X: x, Y: y, G: score}
+				}
+			} else {
+				var err error
+				if g, err = lookup(f.img, symbol.image, x, y, threshold); err != nil {
+					return nil, err
+				}
+			}
+			if g != nil {
+				list = append(list, *g)
+			}
+		}
+	}
+	return list, nil
+}
+
+// settle records the candidates found by the task, and settles all the tasks whose turn came,
+// returning their candidates that don't overlap the certain areas of the tasks before them, nor
+// the ones of the task found before them in the order searched. The candidates skipped by
+// lookupUncertain overlap those areas too, so the ones returned don't depend on the order the
+// tasks finish in
+func (f *parallelFinder) settle(task searchTask, found []GPoint) []settledTask {
+	f.certainMu.Lock()
+	defer f.certainMu.Unlock()
+	if f.pending == nil {
+		f.pending = make(map[int]settledTask)
+	}
+	f.pending[task.index] = settledTask{task.symbol, found}
+
+	var ready []settledTask
+	for {
+		t, ok := f.pending[f.settled]
+		if !ok {
+			return ready
+		}
+		delete(f.pending, f.settled)
+		f.settled++
+
+		var kept []GPoint
+		var areas []image.Rectangle
+		for _, p := range t.found {
+			area := image.Rect(p.X, p.Y, p.X+t.symbol.width, p.Y+t.symbol.height)
+			if overlapsAnyRect(area, f.certain) || overlapsAnyRect(area, areas) {
+				continue
+			}
+			kept = append(kept, p)
+			if p.G >= f.certainty {
+				areas = append(areas, area)
+			}
+		}
+		// appending to a full slice leaves the ones returned by certainAreas untouched
+		f.certain = append(f.certain[:len(f.certain):len(f.certain)], areas...)
+		ready = append(ready, settledTask{t.symbol, kept})
+	}
+}
+
+// certainAreas returns the areas settled so far. The slice returned is not modified afterwards
+func (f *parallelFinder) certainAreas() []image.Rectangle {
+	f.certainMu.RLock()
+	defer f.certainMu.RUnlock()
+	return f.certain[:len(f.certain):len(f.certain)]
+}
+
+func overlapsAnyRect(r image.Rectangle, list []image.Rectangle) bool {
+	for _, l := range list {
+		if r.Overlaps(l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *parallelFinder) merge(ctx context.Context, cs []<-chan lookupResult) <-chan lookupResult {
 	var wg sync.WaitGroup
 	out := make(chan lookupResult)
 
@@ -82,12 +295,9 @@ func (f *parallelFinder) merge(done chan struct{}, cs []<-chan lookupResult) <-c
 		for n := range c {
 			select {
 			case out <- n:
-			case <-done:
+			case <-ctx.Done():
 				return
 			}
-			if n.err != nil {
-				close(done)
-			}
 		}
 	}
 
@@ -105,22 +315,60 @@ func (f *parallelFinder) merge(done chan struct{}, cs []<-chan lookupResult) <-c
 	return out
 }
 
+// lookupAll searches for all symbols, returning the candidates found. All goroutines started
+// are stopped before returning, be it because the search finished, failed or was cancelled.
 func (f *parallelFinder) lookupAll() ([]*fontSymbolLookup, error) {
-	done := make(chan struct{})
-	in := f.prepare(done)
+	parent := f.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	if f.matcher != nil && f.imgGray == nil {
+		f.imgGray = f.img.gray()
+	}
 
+	in := f.prepare(ctx)
 	var workerOutputs = make([]<-chan lookupResult, f.numWorkers)
 	for w := 0; w < f.numWorkers; w++ {
-		workerOutputs[w] = f.addWorker(done, in)
+		workerOutputs[w] = f.addWorker(ctx, in)
 	}
+	out := f.merge(ctx, workerOutputs)
 
-	var result []*fontSymbolLookup
-	for r := range f.merge(done, workerOutputs) {
+	var result, covered []*fontSymbolLookup
+	var err error
+	for r := range out {
 		if r.err != nil {
-			return nil, r.err
+			err = r.err
+			break
 		}
 		result = append(result, r.l)
+		if f.maxCandidates > 0 && len(result) > f.maxCandidates {
+			err = fmt.Errorf("more than the maximum of %d candidates found", f.maxCandidates)
+			break
+		}
+		if f.expected > 0 && !crossesAny(r.l, covered) {
+			covered = append(covered, r.l)
+			if len(covered) >= f.expected {
+				break
+			}
+		}
+	}
+
+	// stop the remaining work, and wait for it to finish, so no goroutine outlives the search
+	cancel()
+	for range out {
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if err := parent.Err(); err != nil {
+		if f.partial {
+			return result, err
+		}
+		return nil, err
 	}
-	close(done)
 	return result, nil
 }
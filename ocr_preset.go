@@ -0,0 +1,54 @@
+package lookup
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Preset is a starting configuration for an OCR, trading recognition speed for accuracy
+type Preset int
+
+const (
+	// PresetFast only accepts close matches, so fewer candidates have to be resolved, and uses
+	// all CPUs. Use it for clean images, rendered with the same font as the symbols
+	PresetFast Preset = iota
+	// PresetBalanced accepts matches a bit further from the symbols, and makes the output
+	// independent from the order the threads find the candidates in
+	PresetBalanced
+	// PresetAccurate first accepts the matches that are easy to tell apart, only lowering the
+	// threshold for the areas still not covered by any symbol, and also searches the symbols
+	// shifted by half a pixel, for text not aligned to the pixel grid. It is several times
+	// slower than the other presets
+	PresetAccurate
+)
+
+func (p Preset) String() string {
+	switch p {
+	case PresetFast:
+		return "fast"
+	case PresetBalanced:
+		return "balanced"
+	case PresetAccurate:
+		return "accurate"
+	}
+	return fmt.Sprintf("Preset(%d)", int(p))
+}
+
+// NewOCRPreset creates a new OCR instance configured with the given preset, using all CPUs
+// available. The options set by the preset can still be changed before loading the fonts.
+// Unknown presets use PresetBalanced
+func NewOCRPreset(preset Preset) *OCR {
+	switch preset {
+	case PresetFast:
+		return NewOCR(0.85, runtime.NumCPU())
+	case PresetAccurate:
+		ocr := NewOCR(0.7, runtime.NumCPU())
+		ocr.Deterministic = true
+		ocr.ThresholdSteps = []float64{0.9, 0.8, 0.7}
+		ocr.SubPixelSteps = 2
+		return ocr
+	}
+	ocr := NewOCR(0.8, runtime.NumCPU())
+	ocr.Deterministic = true
+	return ocr
+}
@@ -0,0 +1,36 @@
+package lookup
+
+import (
+	_ "image/png"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewOCRPreset(t *testing.T) {
+	Convey("Given an image with text", t, func() {
+		img := loadImageColor("testdata/test3.png")
+
+		for _, preset := range []Preset{PresetFast, PresetBalanced, PresetAccurate} {
+			Convey("When using the "+preset.String()+" preset", func() {
+				ocr := NewOCRPreset(preset)
+				_ = ocr.LoadFont("testdata/font_1")
+				text, err := ocr.Recognize(img)
+
+				Convey("It recognizes the text", func() {
+					So(err, ShouldBeNil)
+					So(text, ShouldEqual, "3662\n3 2€/€")
+				})
+			})
+		}
+
+		Convey("The accurate preset accepts matches the fast one rejects", func() {
+			So(NewOCRPreset(PresetAccurate).searchThreshold(), ShouldBeLessThan, NewOCRPreset(PresetFast).searchThreshold())
+		})
+
+		Convey("Unknown presets are balanced", func() {
+			So(NewOCRPreset(Preset(42)).threshold, ShouldEqual, NewOCRPreset(PresetBalanced).threshold)
+			So(Preset(42).String(), ShouldEqual, "Preset(42)")
+		})
+	})
+}
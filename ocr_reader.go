@@ -0,0 +1,30 @@
+package lookup
+
+import (
+	"fmt"
+	"image"
+	"io"
+)
+
+// DecodeError is returned when the image to recognize could not be decoded, to tell it apart
+// from the errors of the recognition itself.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding image: %v", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// RecognizeReader decodes the image read from r and recognizes its text, like Recognize does. PNG
+// and JPEG images are supported, as well as any other format registered with image.RegisterFormat.
+// Failing to decode the image returns a *DecodeError. Use it for images uploaded by users.
+func (o *OCR) RecognizeReader(r io.Reader) (string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return "", &DecodeError{Err: err}
+	}
+	return o.Recognize(img)
+}
@@ -0,0 +1,43 @@
+package lookup
+
+import (
+	"errors"
+	"image"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOCRRecognizeReader(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It recognizes the text of an encoded image", func() {
+			f, _ := os.Open("testdata/test3.png")
+			defer f.Close()
+			text, err := ocr.RecognizeReader(f)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It fails with a DecodeError for data that is not an image", func() {
+			_, err := ocr.RecognizeReader(strings.NewReader("not an image"))
+			var decodeErr *DecodeError
+			So(errors.As(err, &decodeErr), ShouldBeTrue)
+			So(errors.Is(err, image.ErrFormat), ShouldBeTrue)
+		})
+
+		Convey("It fails with other errors when the recognition fails", func() {
+			ocr.MaxImagePixels = 100
+			f, _ := os.Open("testdata/test3.png")
+			defer f.Close()
+			_, err := ocr.RecognizeReader(f)
+			So(err, ShouldNotBeNil)
+			var decodeErr *DecodeError
+			So(errors.As(err, &decodeErr), ShouldBeFalse)
+		})
+	})
+}
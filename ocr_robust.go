@@ -0,0 +1,137 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+// robustStages are the preprocessing steps tried, in order, by RecognizeRobust
+var robustStages = []func(*image.Gray) *image.Gray{
+	func(img *image.Gray) *image.Gray { return img },
+	invert,
+	func(img *image.Gray) *image.Gray { return boxBlur(img, 1) },
+	func(img *image.Gray) *image.Gray { return adaptiveThreshold(img, 7, 10) },
+}
+
+// RecognizeRobust works like Recognize, but if no text is found, or the text found has a low
+// confidence, it retries after preprocessing the image: first inverting it, then smoothing it and
+// finally binarizing it with an adaptive threshold. Recognition stops at the first confident
+// result, otherwise the non-empty result with the best confidence is returned.
+//
+// The confidence of a result is the mean score of its symbols, and it is considered low when
+// it is closer to the threshold (the lowest of the ThresholdSteps, if set) than to a perfect
+// score.
+func (o *OCR) RecognizeRobust(img image.Image) (string, error) {
+	if err := checkSymbols(o.allSymbols); err != nil {
+		return "", err
+	}
+	gray := grayScale(img, o.AlphaOnly).(*image.Gray)
+	minConfidence := (o.searchThreshold() + 1) / 2
+
+	bestText, bestConfidence := "", 0.0
+	for _, preprocess := range robustStages {
+		text, all, err := o.recognizeGray(preprocess(gray), img.Bounds().Min)
+		if err != nil {
+			return "", err
+		}
+		if len(all) == 0 {
+			continue
+		}
+
+		c := o.confidence(all)
+		if c >= minConfidence {
+			return text, nil
+		}
+		if bestText == "" || c > bestConfidence {
+			bestText, bestConfidence = text, c
+		}
+	}
+	return bestText, nil
+}
+
+// RecognizeAutoOrient recognizes the text in an image that may be rotated by any multiple of 90
+// degrees, like scanned pages in landscape or upside-down. All four orientations are tried,
+// and the text of the one with the highest aggregate confidence (the sum of the scores of all
+// its symbols) is returned, along with the clockwise rotation (0, 90, 180 or 270 degrees) that
+// was applied to the image to read it.
+func (o *OCR) RecognizeAutoOrient(img image.Image) (string, int, error) {
+	if err := checkSymbols(o.allSymbols); err != nil {
+		return "", 0, err
+	}
+	gray := grayScale(img, o.AlphaOnly).(*image.Gray)
+
+	bestText, bestRotation, bestScore := "", 0, 0.0
+	for rotation := 0; rotation < 360; rotation += 90 {
+		text, all, err := o.recognizeGray(gray, image.Point{})
+		if err != nil {
+			return "", 0, err
+		}
+		score := o.confidence(all) * float64(len(all))
+		if score > bestScore {
+			bestText, bestRotation, bestScore = text, rotation, score
+		}
+		gray = rotate90(gray)
+	}
+	return bestText, bestRotation, nil
+}
+
+// RecognizeRotations recognizes the text in an image that may be slightly rotated, like a skewed
+// photograph of a label. The image is rotated clockwise by each of the angles (in degrees, so
+// negative ones rotate it counterclockwise), and the text of the angle with the highest aggregate
+// confidence (the sum of the scores of all its symbols) is returned, along with the angle. The
+// first angle wins a tie. Use the angle to deskew the next images of the same source.
+func (o *OCR) RecognizeRotations(img image.Image, angles []float64) (string, float64, error) {
+	if err := checkSymbols(o.allSymbols); err != nil {
+		return "", 0, err
+	}
+	if len(angles) == 0 {
+		return "", 0, fmt.Errorf("no rotation angles to try")
+	}
+	gray := grayAtOrigin(grayScale(img, o.AlphaOnly).(*image.Gray))
+
+	bestText, bestAngle, bestScore := "", angles[0], 0.0
+	for _, angle := range angles {
+		rotated := gray
+		if angle != 0 {
+			rotated = rotate(gray, angle)
+		}
+		text, all, err := o.recognizeGray(rotated, image.Point{})
+		if err != nil {
+			return "", 0, err
+		}
+		score := o.confidence(all) * float64(len(all))
+		if score > bestScore {
+			bestText, bestAngle, bestScore = text, angle, score
+		}
+	}
+	return bestText, bestAngle, nil
+}
+
+// recognizeGray recognizes the text in a gray scale image, using the Fallback if set, also
+// returning the symbols found by this OCR, none if the text is recognized by the Fallback alone
+func (o *OCR) recognizeGray(gray *image.Gray, offset image.Point) (string, []*fontSymbolLookup, error) {
+	if err := o.checkImageSize(gray.Bounds()); err != nil {
+		return "", nil, err
+	}
+	normalized, err := o.normalize(gray)
+	if err != nil {
+		return "", nil, err
+	}
+	bi := newImageBinary(normalized)
+	bi.offset = offset
+	ctx, rect := context.Background(), image.Rect(0, 0, bi.width-1, bi.height-1)
+	if o.fallbackOnly(o.allSymbols) {
+		text, err := o.recognizeWithFallback(ctx, bi, rect, nil)
+		return text, nil, err
+	}
+	all, err := o.detect(ctx, bi, rect, o.allSymbols, false)
+	if err != nil {
+		return "", nil, err
+	}
+	text, err := o.arrangeWithFallback(ctx, bi, rect, all)
+	if err != nil {
+		return "", nil, err
+	}
+	return text, all, nil
+}
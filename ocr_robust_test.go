@@ -0,0 +1,203 @@
+package lookup
+
+import (
+	"image"
+	_ "image/png"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecognizeRobust(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("When the image can be recognized as is", func() {
+			img := loadImageColor("testdata/test3.png")
+
+			Convey("It recognizes the text", func() {
+				text, err := ocr.RecognizeRobust(img)
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When the image has inverted colors", func() {
+			img := invert(loadImageGray("testdata/test3.png").(*image.Gray))
+
+			Convey("It is not recognized by Recognize", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldBeEmpty)
+			})
+
+			Convey("It is recognized by RecognizeRobust", func() {
+				text, err := ocr.RecognizeRobust(img)
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When the threshold is lowered by ThresholdSteps", func() {
+			ocr.ThresholdSteps = []float64{0.95, 0.8}
+			img := invert(loadImageGray("testdata/test3.png").(*image.Gray))
+
+			Convey("It is recognized by RecognizeRobust", func() {
+				text, err := ocr.RecognizeRobust(img)
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+	})
+}
+
+func TestRecognizeAutoOrient(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageGray("testdata/test3.png").(*image.Gray)
+
+		Convey("When the image is upright", func() {
+			text, rotation, err := ocr.RecognizeAutoOrient(img)
+
+			Convey("It recognizes the text without rotating it", func() {
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+				So(rotation, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the image is rotated", func() {
+			text, rotation, err := ocr.RecognizeAutoOrient(rotate90(img))
+
+			Convey("It recognizes the text and reports the rotation used to read it", func() {
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+				So(rotation, ShouldEqual, 270)
+			})
+		})
+
+		Convey("When the image is upside-down", func() {
+			text, rotation, _ := ocr.RecognizeAutoOrient(rotate90(rotate90(img)))
+
+			Convey("It recognizes the text and reports the rotation used to read it", func() {
+				So(text, ShouldEqual, "3662\n3 2€/€")
+				So(rotation, ShouldEqual, 180)
+			})
+		})
+	})
+}
+
+func TestRecognizeRotations(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.7)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageGray("testdata/test3.png").(*image.Gray)
+		angles := []float64{-4, 0, 4}
+
+		Convey("When the image is straight", func() {
+			text, angle, err := ocr.RecognizeRotations(img, angles)
+
+			Convey("It recognizes the text without rotating it", func() {
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+				So(angle, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the image is skewed", func() {
+			text, angle, err := ocr.RecognizeRotations(rotate(img, -4), angles)
+
+			Convey("It recognizes the text and reports the angle used to read it", func() {
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+				So(angle, ShouldEqual, 4)
+			})
+		})
+
+		Convey("It fails without angles to try", func() {
+			_, _, err := ocr.RecognizeRotations(img, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestOCRContrastTile(t *testing.T) {
+	Convey("Given an image with a faded part", t, func() {
+		img := loadImageGray("testdata/test3.png").(*image.Gray)
+		faded := image.NewGray(img.Bounds())
+		for i, v := range img.Pix {
+			if i%img.Stride >= 48 {
+				v = uint8(float64(v) * 0.08)
+			}
+			faded.Pix[i] = v
+		}
+		contrast := func(img *image.Gray) int {
+			low, high := 255, 0
+			for i, v := range img.Pix {
+				if i%img.Stride >= 48 {
+					low, high = min(low, int(v)), max(high, int(v))
+				}
+			}
+			return high - low
+		}
+
+		Convey("It restores the contrast of the faded part", func() {
+			So(contrast(faded), ShouldBeLessThan, 25)
+			So(contrast(equalizeContrast(faded, 16)), ShouldBeGreaterThan, 3*contrast(faded))
+		})
+
+		Convey("It recognizes the text", func() {
+			ocr := NewOCR(0.8)
+			_ = ocr.LoadFont("testdata/font_1")
+			ocr.ContrastTile = 16
+			text, err := ocr.Recognize(faded)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+	})
+}
+
+func TestOCRBinaryThreshold(t *testing.T) {
+	Convey("Given a faded image", t, func() {
+		img := loadImageGray("testdata/test3.png").(*image.Gray)
+		faded := image.NewGray(img.Bounds())
+		for i, v := range img.Pix {
+			faded.Pix[i] = uint8(float64(v) * 0.06)
+		}
+		// the glyphs of the font keep their anti-aliasing, so they don't match binarized text as well
+		ocr := NewOCR(0.7)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It picks a threshold between the background and the ink", func() {
+			threshold := otsuThreshold(faded)
+			So(threshold, ShouldBeGreaterThan, 3)
+			So(threshold, ShouldBeLessThanOrEqualTo, 15)
+			for _, v := range binarize(faded, threshold).Pix {
+				So(v == 0 || v == 255, ShouldBeTrue)
+			}
+		})
+
+		Convey("It recognizes the text binarizing it with a given threshold", func() {
+			ocr.BinaryThreshold = 8
+			text, err := ocr.Recognize(faded)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It recognizes the text binarizing it with Otsu's threshold", func() {
+			ocr.BinaryThreshold = OtsuThreshold
+			text, err := ocr.Recognize(faded)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It fails with a negative threshold other than Otsu's", func() {
+			ocr.BinaryThreshold = -2
+			_, err := ocr.Recognize(faded)
+			So(err, ShouldNotBeNil)
+			_, err = ocr.RecognizeDetailed(faded)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,44 @@
+package lookup
+
+import (
+	"context"
+	"image"
+)
+
+// RecognitionStats are the numbers behind a recognition, to tune the threshold or decide if the
+// text recognized can be trusted.
+type RecognitionStats struct {
+	// The number of candidates scoring above the threshold, before removing the overlapping ones
+	RawMatches int
+	// The number of symbols the text was composed from
+	FinalMatches int
+	// The lowest, highest and mean score of the symbols the text was composed from. Zero if no
+	// symbol was found
+	MinG, MaxG, MeanG float64
+}
+
+// RecognizeStats works like Recognize, but also returns the statistics of the recognition.
+func (o *OCR) RecognizeStats(img image.Image) (string, RecognitionStats, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return "", RecognitionStats{}, err
+	}
+	found, err := o.find(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols, false)
+	if err != nil {
+		return "", RecognitionStats{}, err
+	}
+
+	stats := RecognitionStats{RawMatches: len(found)}
+	all := o.confidentLines(bi, o.filter(found))
+	stats.FinalMatches = len(all)
+	for i, s := range all {
+		if i == 0 || s.g < stats.MinG {
+			stats.MinG = s.g
+		}
+		if i == 0 || s.g > stats.MaxG {
+			stats.MaxG = s.g
+		}
+		stats.MeanG += s.g / float64(len(all))
+	}
+	return o.arrange(bi, all), stats, nil
+}
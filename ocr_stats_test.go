@@ -0,0 +1,44 @@
+package lookup
+
+import (
+	"image"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOCRRecognizeStats(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("When I recognize an image with statistics", func() {
+			img := loadImageColor("testdata/test3.png")
+			text, stats, err := ocr.RecognizeStats(img)
+
+			Convey("It recognizes the text", func() {
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+
+			Convey("It counts the candidates before and after removing the overlapping ones", func() {
+				So(stats.FinalMatches, ShouldEqual, 9)
+				So(stats.RawMatches, ShouldBeGreaterThan, stats.FinalMatches)
+			})
+
+			Convey("It aggregates the scores of the symbols recognized", func() {
+				matches, _ := ocr.RecognizeDetailed(img)
+				So(stats.MinG, ShouldAlmostEqual, 0.883, 0.001)
+				So(stats.MaxG, ShouldAlmostEqual, 1.0, 0.000001)
+				So(stats.MeanG, ShouldAlmostEqual, ocr.Confidence(matches), 0.000001)
+			})
+		})
+
+		Convey("It returns empty statistics for an image without text", func() {
+			text, stats, err := ocr.RecognizeStats(image.NewGray(image.Rect(0, 0, 40, 30)))
+			So(err, ShouldBeNil)
+			So(text, ShouldBeEmpty)
+			So(stats, ShouldResemble, RecognitionStats{})
+		})
+	})
+}
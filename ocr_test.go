@@ -1,9 +1,28 @@
 package lookup
 
 import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
 	_ "image/png"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -46,6 +65,15 @@ func TestOCR(t *testing.T) {
 				})
 			})
 
+			Convey("And when I recognize only the top of an image", func() {
+				img := loadImageColor("testdata/test3.png")
+				text, _ := ocr.RecognizeTop(img, 20)
+
+				Convey("It only recognizes the text inside the top part", func() {
+					So(text, ShouldEqual, "3662")
+				})
+			})
+
 			Convey("And when I pass an subimage to be recognized", func() {
 				img := loadImageColor("testdata/full.png")
 				text, _ := ocr.Recognize(img.(*image.NRGBA).SubImage(image.Rect(1280, 646, 1280+61, 646+31)))
@@ -59,6 +87,2385 @@ func TestOCR(t *testing.T) {
 	})
 }
 
+func TestOCRRecognizeRegion(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/full.png")
+
+		Convey("It only recognizes the text inside the region", func() {
+			text, err := ocr.RecognizeRegion(img, image.Rect(1280, 646, 1280+61, 646+31))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "4339")
+		})
+
+		Convey("It clamps the region to the image bounds", func() {
+			img := loadImageColor("testdata/test3.png")
+			text, err := ocr.RecognizeRegion(img, image.Rect(-10, -10, 100, 20))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662")
+		})
+
+		Convey("It returns an error if the region is outside the image", func() {
+			_, err := ocr.RecognizeRegion(img, image.Rect(-20, -20, -10, -10))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestOCRLoadFontAsync(t *testing.T) {
+	Convey("Given an OCR with several threads", t, func() {
+		ocr := NewOCR(0.8, 4)
+
+		Convey("When I load a font", func() {
+			var calls [][2]int
+			err := ocr.LoadFontAsync("testdata/font_1", func(done, total int) {
+				calls = append(calls, [2]int{done, total})
+			})
+
+			Convey("It reports the progress after each file", func() {
+				So(err, ShouldBeNil)
+				So(calls, ShouldHaveLength, 13)
+				for i, c := range calls {
+					So(c, ShouldResemble, [2]int{i + 1, 13})
+				}
+			})
+
+			Convey("It loads the symbols in the order of the files", func() {
+				So(ocr.fontFamilies["font_1"], ShouldHaveLength, 13)
+				So(ocr.allSymbols[0].symbol, ShouldEqual, "/")
+				text, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When a file is not an image", func() {
+			dir := t.TempDir()
+			_ = ioutil.WriteFile(filepath.Join(dir, "a.png"), []byte("not an image"), 0600)
+			err := ocr.LoadFontAsync(dir, nil)
+
+			Convey("It returns an error", func() {
+				So(err, ShouldNotBeNil)
+				So(ocr.allSymbols, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestOCRLoadFontJSON(t *testing.T) {
+	Convey("Given a JSON fontset", t, func() {
+		entries := map[string]string{}
+		files, _ := ioutil.ReadDir("testdata/font_1")
+		for _, f := range files {
+			if strings.HasPrefix(f.Name(), ".") {
+				continue
+			}
+			data, _ := ioutil.ReadFile("testdata/font_1/" + f.Name())
+			name, _ := url.QueryUnescape(strings.TrimSuffix(f.Name(), ".png"))
+			entries[name] = base64.StdEncoding.EncodeToString(data)
+		}
+		fontJSON, _ := json.Marshal(entries)
+
+		Convey("When I load it in an OCR", func() {
+			ocr := NewOCR(0.8)
+			err := ocr.LoadFontJSON(bytes.NewReader(fontJSON))
+
+			Convey("It loads all symbols", func() {
+				So(err, ShouldBeNil)
+				So(ocr.allSymbols, ShouldHaveLength, 13)
+			})
+
+			Convey("It recognizes text with them", func() {
+				text, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When an image is not valid base64", func() {
+			ocr := NewOCR(0.8)
+			err := ocr.LoadFontJSON(strings.NewReader(`{"0": "not base64!"}`))
+
+			Convey("It returns an error", func() {
+				So(err, ShouldNotBeNil)
+				So(ocr.allSymbols, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+//go:embed testdata/font_1
+var embeddedFonts embed.FS
+
+func TestOCRLoadFontFS(t *testing.T) {
+	Convey("Given a fontset embedded in the binary", t, func() {
+		ocr := NewOCR(0.8)
+
+		Convey("When I load it in an OCR", func() {
+			err := ocr.LoadFontFS(embeddedFonts, "testdata/font_1")
+
+			Convey("It loads all symbols in a family named after the folder", func() {
+				So(err, ShouldBeNil)
+				So(ocr.allSymbols, ShouldHaveLength, 13)
+				So(ocr.fontFamilies["font_1"], ShouldHaveLength, 13)
+			})
+
+			Convey("It recognizes text with them", func() {
+				text, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When the folder does not exist", func() {
+			err := ocr.LoadFontFS(embeddedFonts, "testdata/font_2")
+
+			Convey("It returns an error", func() {
+				So(err, ShouldNotBeNil)
+				So(ocr.allSymbols, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestOCRLoadFontRelative(t *testing.T) {
+	Convey("Given a fontset in the parent of the working directory", t, func() {
+		dir := filepath.Join(t.TempDir(), "font")
+		So(os.MkdirAll(filepath.Join(dir, "sub"), 0o755), ShouldBeNil)
+		files, _ := os.ReadDir("testdata/font_1")
+		for _, f := range files {
+			data, _ := os.ReadFile(filepath.Join("testdata/font_1", f.Name()))
+			So(os.WriteFile(filepath.Join(dir, f.Name()), data, 0o644), ShouldBeNil)
+		}
+		wd, _ := os.Getwd()
+		So(os.Chdir(filepath.Join(dir, "sub")), ShouldBeNil)
+		defer func() { _ = os.Chdir(wd) }()
+
+		Convey("It loads it from the relative path", func() {
+			ocr := NewOCR(0.8)
+			So(ocr.LoadFont(".."), ShouldBeNil)
+			So(ocr.allSymbols, ShouldHaveLength, 13)
+			So(ocr.LoadFontAsync("..", nil), ShouldBeNil)
+			So(ocr.allSymbols, ShouldHaveLength, 26)
+		})
+	})
+}
+
+func TestOCRThresholdSteps(t *testing.T) {
+	Convey("Given a low score big symbol overlapping a high score small one", t, func() {
+		small := NewFontSymbol("s", image.NewGray(image.Rect(0, 0, 5, 10)))
+		big := NewFontSymbol("B", image.NewGray(image.Rect(0, 0, 10, 14)))
+		candidates := func() []*fontSymbolLookup {
+			return []*fontSymbolLookup{
+				newFontSymbolLookup(small, 2, 2, 0.99),
+				newFontSymbolLookup(big, 0, 0, 0.75),
+			}
+		}
+
+		Convey("Without threshold steps, the bigger symbol wins", func() {
+			ocr := NewOCR(0.7)
+			So(ocr.filter(candidates())[0].fs, ShouldEqual, big)
+		})
+
+		Convey("With threshold steps, the confident symbol is accepted first", func() {
+			ocr := NewOCR(0.7)
+			ocr.ThresholdSteps = []float64{0.7, 0.95}
+			all := ocr.filter(candidates())
+			So(all, ShouldHaveLength, 1)
+			So(all[0].fs, ShouldEqual, small)
+		})
+	})
+
+	Convey("Given an OCR with threshold steps", t, func() {
+		ocr := NewOCR(0.99)
+		ocr.ThresholdSteps = []float64{0.95, 0.8}
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It searches using the lowest step", func() {
+			So(ocr.searchThreshold(), ShouldEqual, 0.8)
+		})
+
+		Convey("It recognizes the text", func() {
+			text, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+	})
+}
+
+func TestOCRMirrored(t *testing.T) {
+	Convey("Given a mirrored image", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := flipHorizontal(loadImageGray("testdata/test3.png").(*image.Gray))
+
+		Convey("It does not recognize any text by default", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldBeEmpty)
+		})
+
+		Convey("When the OCR searches for mirrored symbols", func() {
+			ocr.Mirrored = true
+
+			Convey("It recognizes the mirrored symbols with their original labels", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "2663\n€/€2 3")
+			})
+
+			Convey("It reports the matches as mirrored", func() {
+				matches, _ := ocr.RecognizeDetailed(img)
+				So(matches, ShouldHaveLength, 9)
+				for _, m := range matches {
+					So(m.Mirrored, ShouldBeTrue)
+				}
+			})
+
+			Convey("It still recognizes non mirrored text", func() {
+				text, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+	})
+}
+
+func TestOCRFamilyWeights(t *testing.T) {
+	Convey("Given an OCR with two families with the same symbols", t, func() {
+		ocr := NewOCR(0.8)
+		primary, _ := loadFont("testdata/font_1")
+		fallback, _ := loadFont("testdata/font_1")
+		ocr.AddFontFamily("fallback", fallback...)
+		ocr.AddFontFamily("primary", primary...)
+		bi := newImageBinary(loadImageGray("testdata/test3.png"))
+
+		for _, family := range []string{"primary", "fallback"} {
+			family := family
+			Convey("When the "+family+" family has a bigger weight", func() {
+				ocr.FamilyWeights = map[string]float64{family: 1.1}
+				found, _ := ocr.find(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), ocr.allSymbols, false)
+				all := ocr.filter(found)
+
+				Convey("It keeps only the symbols of the "+family+" family", func() {
+					So(all, ShouldHaveLength, 9)
+					for _, s := range all {
+						So(s.fs.family, ShouldEqual, family)
+					}
+				})
+			})
+		}
+	})
+}
+
+func TestOCRExpandLigatures(t *testing.T) {
+	Convey("Given an OCR with a ligature symbol", t, func() {
+		ocr := NewOCR(0.8)
+		symbols, _ := loadFont("testdata/font_1")
+		for _, s := range symbols {
+			if s.symbol == "2" {
+				s.symbol = "ﬁ"
+			}
+		}
+		ocr.AddSymbols(symbols...)
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It outputs the ligature by default", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "366ﬁ\n3 ﬁ€/€")
+		})
+
+		Convey("It outputs the expanded ligature when configured", func() {
+			ocr.ExpandLigatures = map[string]string{"ﬁ": "fi"}
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "366fi\n3 fi€/€")
+		})
+	})
+}
+
+func TestOCRRecognizeFamilies(t *testing.T) {
+	Convey("Given an OCR with more than one font family", t, func() {
+		ocr := NewOCR(0.8)
+		digits, _ := loadFont("testdata/font_1")
+		var others []*FontSymbol
+		for i := 0; i < len(digits); i++ {
+			if digits[i].symbol == "/" || digits[i].symbol == "€" {
+				others = append(others, digits[i])
+				digits = deleteFontSymbol(digits, i)
+				i--
+			}
+		}
+		ocr.AddFontFamily("digits", digits...)
+		ocr.AddFontFamily("others", others...)
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It only recognizes the symbols of the given families", func() {
+			text, err := ocr.RecognizeFamilies(img, "digits")
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2")
+
+			text, err = ocr.RecognizeFamilies(img, "others")
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "€/€")
+		})
+
+		Convey("It recognizes the symbols of all given families", func() {
+			text, err := ocr.RecognizeFamilies(img, "digits", "others")
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It returns an error for unknown families", func() {
+			_, err := ocr.RecognizeFamilies(img, "digits", "unknown")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func deleteFontSymbol(list []*FontSymbol, i int) []*FontSymbol {
+	return append(list[:i], list[i+1:]...)
+}
+
+func TestOCRRecognizeTimed(t *testing.T) {
+	Convey("Given an OCR searching for mirrored symbols too", t, func() {
+		ocr := NewOCR(0.8, 3)
+		ocr.Mirrored = true
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("When I recognize an image with timings", func() {
+			text, timings, err := ocr.RecognizeTimed(loadImageColor("testdata/test3.png"))
+
+			Convey("It recognizes the text", func() {
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+
+			Convey("It reports the timings of each loaded symbol, slowest first", func() {
+				So(timings, ShouldHaveLength, 13)
+				for i, t := range timings {
+					So(ocr.allSymbols, ShouldContain, t.Symbol)
+					if i > 0 {
+						So(t.Duration, ShouldBeLessThanOrEqualTo, timings[i-1].Duration)
+					}
+				}
+			})
+		})
+	})
+}
+
+func TestOCRRecognizeProgress(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8, 4)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It reports the progress of each symbol searched, in order", func() {
+			var done, totals []int
+			text, err := ocr.RecognizeProgress(img, func(d, total int) {
+				done, totals = append(done, d), append(totals, total)
+			})
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			So(done, ShouldHaveLength, 13)
+			for i, d := range done {
+				So(d, ShouldEqual, i+1)
+				So(totals[i], ShouldEqual, 13)
+			}
+		})
+
+		Convey("It counts each band as a search when searching in bands", func() {
+			ocr.ImageBands = 3
+			last, total := 0, 0
+			_, err := ocr.RecognizeProgress(img, func(d, t int) {
+				last, total = d, t
+			})
+			So(err, ShouldBeNil)
+			So(total, ShouldEqual, 39)
+			So(last, ShouldEqual, total)
+		})
+
+		Convey("It works without a callback", func() {
+			text, err := ocr.RecognizeProgress(img, nil)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+	})
+}
+
+func TestOCRSymbolGroups(t *testing.T) {
+	Convey("Given a low score big symbol overlapping a high score small one", t, func() {
+		small := NewFontSymbol("s", image.NewGray(image.Rect(0, 0, 5, 10)))
+		big := NewFontSymbol("B", image.NewGray(image.Rect(0, 0, 10, 14)))
+		candidates := func() []*fontSymbolLookup {
+			return []*fontSymbolLookup{
+				newFontSymbolLookup(small, 2, 2, 0.99),
+				newFontSymbolLookup(big, 0, 0, 0.75),
+			}
+		}
+		ocr := NewOCR(0.7)
+
+		Convey("When they are different symbols, the bigger one wins", func() {
+			So(ocr.filter(candidates())[0].fs, ShouldEqual, big)
+		})
+
+		Convey("When they are grouped under the same symbol, the best score wins", func() {
+			ocr.AddSymbolGroup("x", small, big)
+			all := ocr.filter(candidates())
+			So(all, ShouldHaveLength, 1)
+			So(all[0].fs, ShouldEqual, small)
+			So(all[0].fs.symbol, ShouldEqual, "x")
+		})
+	})
+
+	Convey("Given a symbol group with alternative images for a symbol", t, func() {
+		ocr := NewOCR(0.8)
+		ocr.AddSymbolGroup("#",
+			NewFontSymbol("", loadImageGray("testdata/font_1/3.png")),
+			NewFontSymbol("", loadImageGray("testdata/font_1/6.png")),
+		)
+
+		Convey("It recognizes any of them as the group symbol", func() {
+			text, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+			So(text, ShouldEqual, "###\n#")
+		})
+	})
+}
+
+func TestOCRMatchesInRegion(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/full.png").(*image.NRGBA).SubImage(image.Rect(1200, 600, 1400, 700))
+
+		Convey("When I get the matches in a region of the image", func() {
+			matches, err := ocr.MatchesInRegion(img, image.Rect(1280, 646, 1280+61, 646+31))
+			So(err, ShouldBeNil)
+
+			Convey("It returns only the symbols inside the region, in image coordinates", func() {
+				So(matches, ShouldHaveLength, 4)
+				var symbols []string
+				for _, m := range matches {
+					So(m.Rect.In(image.Rect(1280, 646, 1280+61, 646+31)), ShouldBeTrue)
+					symbols = append(symbols, m.Symbol)
+				}
+				sort.Strings(symbols)
+				So(symbols, ShouldResemble, []string{"3", "3", "4", "9"})
+			})
+		})
+
+		Convey("When the region is outside the image", func() {
+			_, err := ocr.MatchesInRegion(img, image.Rect(0, 0, 100, 100))
+
+			Convey("It returns an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestOCRMaxLineSize(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("When lines have a maximum number of symbols", func() {
+			ocr.MaxLineSymbols = 1
+
+			Convey("It breaks the lines at the spaces after the maximum", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3\n2€/€")
+			})
+		})
+
+		Convey("When lines have a maximum width", func() {
+			ocr.MaxLineWidth = 15
+
+			Convey("It breaks the lines at the spaces that would exceed the maximum", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3\n2€/€")
+			})
+		})
+	})
+}
+
+func TestOCRProportionalSpaces(t *testing.T) {
+	Convey("Given symbols separated by gaps of different widths", t, func() {
+		fs := NewFontSymbol("a", image.NewGray(image.Rect(0, 0, 10, 10)))
+		all := []*fontSymbolLookup{
+			newFontSymbolLookup(fs, 0, 0, 1),
+			newFontSymbolLookup(fs, 24, 0, 1),
+			newFontSymbolLookup(fs, 60, 0, 1),
+			newFontSymbolLookup(fs, 70, 0, 1),
+		}
+		ocr := NewOCR(0.8)
+
+		Convey("By default, any gap produces a single space", func() {
+			So(ocr.arrange(nil, all), ShouldEqual, "a a aa")
+		})
+
+		Convey("With proportional spaces, each gap produces spaces according to its width", func() {
+			ocr.ProportionalSpaces = true
+			So(ocr.arrange(nil, all), ShouldEqual, "a a   aa")
+		})
+	})
+}
+
+func TestOCRRecognizeDPI(t *testing.T) {
+	Convey("Given a font rendered at 96 DPI", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		ocr.SetFamilyDPI("font_1", 96)
+
+		Convey("And an image at twice the resolution", func() {
+			img := scale(loadImageGray("testdata/test3.png").(*image.Gray), 2, 2)
+
+			Convey("It does not recognize the text at the font resolution", func() {
+				text, _ := ocr.RecognizeDPI(img, 96)
+				So(text, ShouldNotEqual, "3662\n3 2€/€")
+			})
+
+			Convey("It recognizes the text when given the image resolution", func() {
+				text, err := ocr.RecognizeDPI(img, 192)
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("It returns an error for an invalid DPI", func() {
+			_, err := ocr.RecognizeDPI(loadImageColor("testdata/test3.png"), 0)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("It rejects an invalid DPI for the font, keeping the one set before", func() {
+			So(ocr.SetFamilyDPI("font_1", 0), ShouldNotBeNil)
+			So(ocr.SetFamilyDPI("font_1", -96), ShouldNotBeNil)
+			So(ocr.SetFamilyDPI("font_1", math.NaN()), ShouldNotBeNil)
+			So(ocr.familyDPI["font_1"], ShouldEqual, 96)
+		})
+	})
+}
+
+func TestOCRAcceptFunc(t *testing.T) {
+	Convey("Given an OCR with an AcceptFunc", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		var candidates []Match
+		ocr.AcceptFunc = func(m Match) bool {
+			candidates = append(candidates, m)
+			return m.Symbol != "2" || m.Rect.Min.Y < 20
+		}
+
+		Convey("It discards the candidates rejected by the function", func() {
+			text, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+			So(text, ShouldEqual, "3662\n3 €/€")
+		})
+
+		Convey("It passes candidates in the coordinates of the image", func() {
+			img := loadImageColor("testdata/full.png").(*image.NRGBA).SubImage(image.Rect(1280, 646, 1280+61, 646+31))
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "4339")
+			for _, m := range candidates {
+				So(m.Rect.In(img.Bounds()), ShouldBeTrue)
+			}
+		})
+	})
+}
+
+func TestOCRMinMatches(t *testing.T) {
+	Convey("Given an OCR with a minimum number of matches", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It returns the text when enough symbols are found", func() {
+			ocr.MinMatches = 9
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It returns an empty text when too few symbols are found", func() {
+			ocr.MinMatches = 10
+			text, err := ocr.Recognize(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestOCRSubPixelSteps(t *testing.T) {
+	Convey("Given an image with text not aligned to the pixel grid", t, func() {
+		img := shift(loadImageGray("testdata/test3.png").(*image.Gray), 0.5, 0.5)
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It misses symbols by default", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "36 2\n3 2€/")
+		})
+
+		Convey("It recognizes all symbols when searching sub-pixel positions", func() {
+			ocr.SubPixelSteps = 2
+			text, err := ocr.Recognize(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+	})
+}
+
+func TestOCRRecognizeGray(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It recognizes the text in a gray scale image", func() {
+			text, err := ocr.RecognizeGray(loadImageGray("testdata/test3.png").(*image.Gray))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It recognizes the text in a sub image", func() {
+			gray := ensureGrayScale(loadImageColor("testdata/full.png")).(*image.Gray)
+			text, err := ocr.RecognizeGray(gray.SubImage(image.Rect(1280, 646, 1280+61, 646+31)).(*image.Gray))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "4339")
+		})
+	})
+}
+
+func TestOCRTransitionCost(t *testing.T) {
+	Convey("Given an OCR finding ambiguous candidates", t, func() {
+		ocr := NewOCR(0.6)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It picks the best scoring symbols by default", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It picks the candidates avoiding costly transitions", func() {
+			ocr.TransitionCost = func(prev, next string) float64 {
+				if (prev == "3" && next == "6") || next == "/" {
+					return 1
+				}
+				return 0
+			}
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3862\n3 2€7€")
+		})
+
+		Convey("It does not apply the costs across lines", func() {
+			ocr.TransitionCost = func(prev, next string) float64 {
+				if prev == "2" && next == "3" {
+					return 1
+				}
+				return 0
+			}
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+	})
+}
+
+func TestOCRLines(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It returns the area of each line of text", func() {
+			lines, err := ocr.Lines(loadImageColor("testdata/test3.png"))
+			So(err, ShouldBeNil)
+			So(lines, ShouldResemble, []image.Rectangle{image.Rect(6, 4, 47, 18), image.Rect(12, 25, 79, 41)})
+		})
+
+		Convey("It returns the lines in the coordinates of the image", func() {
+			img := loadImageColor("testdata/full.png").(*image.NRGBA).SubImage(image.Rect(1280, 646, 1280+61, 646+31))
+			lines, err := ocr.Lines(img)
+			So(err, ShouldBeNil)
+			So(lines, ShouldHaveLength, 1)
+			So(lines[0].In(img.Bounds()), ShouldBeTrue)
+		})
+
+		Convey("It returns no lines for an image without text", func() {
+			lines, err := ocr.Lines(newGrayImage(20, 20, nil))
+			So(err, ShouldBeNil)
+			So(lines, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestOCRAspectRatioPenalty(t *testing.T) {
+	Convey("Given an OCR with a low threshold", t, func() {
+		ocr := NewOCR(0.5)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It finds symbols in regions of a different shape by default", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n7372€/€")
+		})
+
+		Convey("It discards them when penalizing the aspect ratio", func() {
+			ocr.AspectRatioPenalty = 0.2
+			matches, err := ocr.RecognizeDetailed(img)
+			So(err, ShouldBeNil)
+			So(matches, ShouldHaveLength, 9)
+			for _, m := range matches {
+				So(m.G, ShouldBeGreaterThan, 0.8)
+			}
+		})
+	})
+}
+
+func TestOCRRecognizeParallel(t *testing.T) {
+	Convey("Given an OCR created with a single thread", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It recognizes the text using more threads", func() {
+			text, err := ocr.RecognizeParallel(img, 4)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			So(ocr.numThreads, ShouldEqual, 1)
+		})
+
+		Convey("It fails with an invalid number of threads", func() {
+			_, err := ocr.RecognizeParallel(img, 0)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestOCRPreserveLeadingSpace(t *testing.T) {
+	Convey("Given an OCR recognizing a region with indented text", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/full.png").(*image.NRGBA).SubImage(image.Rect(1280-25, 646, 1280+61, 646+31))
+
+		Convey("It drops the leading space by default", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "4339")
+		})
+
+		Convey("It keeps the leading space when asked to", func() {
+			ocr.PreserveLeadingSpace = true
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "   4339")
+		})
+
+		Convey("It keeps the indentation of every line", func() {
+			ocr.PreserveLeadingSpace = true
+			text, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+			So(text, ShouldEqual, "3662\n 3 2€/€")
+		})
+
+		Convey("It measures the indentation from the left edge of the region recognized", func() {
+			ocr.PreserveLeadingSpace = true
+			text, _ := ocr.RecognizeRegion(loadImageColor("testdata/full.png"), image.Rect(1280-25, 646, 1280+61, 646+31))
+			So(text, ShouldEqual, "   4339")
+		})
+	})
+
+	Convey("Given symbols with invalid advances", t, func() {
+		fs := NewFontSymbolOpts("3", loadImageGray("testdata/font_1/3.png"), &NewFontSymbolOptions{Advance: -10})
+
+		Convey("A negative advance in the options is ignored", func() {
+			So(fs.Advance(), ShouldEqual, fs.width)
+		})
+
+		Convey("A negative advance is rejected", func() {
+			So(fs.SetAdvance(-10), ShouldNotBeNil)
+			So(fs.Advance(), ShouldEqual, fs.width)
+		})
+	})
+}
+
+func TestOCRFallback(t *testing.T) {
+	Convey("Given an OCR missing a symbol, with a fallback having it", t, func() {
+		ocr := NewOCR(0.6)
+		symbols, _ := loadFont("testdata/font_1")
+		for i := 0; i < len(symbols); i++ {
+			if symbols[i].symbol == "€" {
+				symbols = deleteFontSymbol(symbols, i)
+				i--
+			}
+		}
+		ocr.AddFontFamily("font_1", symbols...)
+		fallback := NewOCR(0.8)
+		_ = fallback.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It misrecognizes the line with the missing symbol", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldNotEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It recognizes the lines with a low confidence with the fallback", func() {
+			ocr.Fallback = fallback
+			ocr.FallbackConfidence = 0.95
+			text, err := ocr.Recognize(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It recognizes the whole image with the fallback when nothing was found", func() {
+			empty := NewOCR(0.8)
+			empty.Fallback = fallback
+			text, err := empty.Recognize(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It recognizes the text with the fallback alone, or fails where it isn't used", func() {
+			empty := NewOCR(0.8)
+			empty.Fallback = fallback
+			text, _, err := empty.RecognizeTimed(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			text, err = empty.RecognizeProgress(img, nil)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			text, err = empty.RecognizeTimeout(img, time.Minute)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			text, err = empty.RecognizeTop(img, 20)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662")
+			text, err = empty.RecognizeGray(ensureGrayScale(img).(*image.Gray))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+
+			_, err = empty.RecognizeDetailed(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = empty.CountGlyphs(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = empty.RecognizeResult(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = empty.Lines(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = empty.RecognizeMarkdown(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, _, err = empty.RecognizeStats(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = empty.RecognizeHOCR(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = empty.MatchesInRegion(img, img.Bounds())
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = empty.RecognizeMaskImage(img, image.NewGray(img.Bounds()))
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = empty.ConfidenceMap(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = empty.RecognizeRobust(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, _, err = empty.RecognizeAutoOrient(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, _, err = empty.RecognizeRotations(img, []float64{0})
+			So(err, ShouldEqual, ErrNoSymbols)
+		})
+
+		Convey("It recognizes the lines with a low confidence of a gray scale image with the fallback", func() {
+			ocr.Fallback = fallback
+			ocr.FallbackConfidence = 0.95
+			text, err := ocr.RecognizeGray(ensureGrayScale(img).(*image.Gray))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It recognizes the lines with a low confidence with the fallback while timing them", func() {
+			ocr.Fallback = fallback
+			ocr.FallbackConfidence = 0.95
+			text, _, err := ocr.RecognizeTimed(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			text, err = ocr.RecognizeProgress(img, nil)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It fails when the OCR is its own fallback", func() {
+			ocr.Fallback = ocr
+			_, err := ocr.Recognize(img)
+			So(err, ShouldEqual, errFallbackCycle)
+		})
+
+		Convey("It fails when the fallback falls back to the OCR", func() {
+			ocr.Fallback = fallback
+			fallback.Fallback = ocr
+			_, err := ocr.Recognize(img)
+			So(err, ShouldEqual, errFallbackCycle)
+		})
+	})
+}
+
+func TestOCRSameLabelSymbols(t *testing.T) {
+	Convey("Given a font with two images for the € symbol", t, func() {
+		symbols, _ := loadFont("testdata/font_1")
+		var euros []*FontSymbol
+		for _, s := range symbols {
+			if s.symbol == "€" {
+				euros = append(euros, s)
+			}
+		}
+		So(euros, ShouldHaveLength, 2)
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It recognizes each € with a different image", func() {
+			ocr := NewOCR(0.8)
+			ocr.AddFontFamily("font_1", symbols...)
+			bi, _ := ocr.prepare(img)
+			found, _ := ocr.find(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), ocr.allSymbols, false)
+			var matched []*FontSymbol
+			for _, l := range ocr.filter(found) {
+				if l.fs.symbol == "€" {
+					matched = append(matched, l.fs.original())
+				}
+			}
+			So(matched, ShouldResemble, []*FontSymbol{euros[1], euros[0]})
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It recognizes each € with images added as variants", func() {
+			ocr := NewOCR(0.8)
+			for _, s := range symbols {
+				if s.symbol != "€" {
+					ocr.AddSymbols(s)
+				}
+			}
+			euro := loadImageGray("testdata/font_1/%E2%82%AC.png")
+			variant := ocr.AddSymbolVariant("€", loadImageGray("testdata/font_1/%E2%82%AC%E2%80%8B.png"))
+			So(variant.symbol, ShouldEqual, "€")
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2 /€")
+
+			ocr.AddSymbolVariant("€", euro)
+			text, _ = ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It misses a € when any of the images is not loaded", func() {
+			for i, missing := range []string{"3662\n3 2€/", "3662\n3 2 /€"} {
+				ocr := NewOCR(0.8)
+				for _, s := range symbols {
+					if s != euros[i] {
+						ocr.AddSymbols(s)
+					}
+				}
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, missing)
+			}
+		})
+	})
+}
+
+func TestOCRScoreMargin(t *testing.T) {
+	Convey("Given an OCR with a low threshold", t, func() {
+		ocr := NewOCR(0.6)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It keeps symbols barely better than a different candidate by default", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It discards the symbols not exceeding other candidates by the margin", func() {
+			ocr.ScoreMargin = 0.1
+			matches, err := ocr.RecognizeDetailed(img)
+			So(err, ShouldBeNil)
+			So(matches, ShouldHaveLength, 8)
+			So(matches[1].Rect.Min, ShouldResemble, image.Pt(26, 4))
+		})
+	})
+}
+
+func TestOCRDebugFamilyPrefix(t *testing.T) {
+	Convey("Given an OCR with two font families", t, func() {
+		ocr := NewOCR(0.8)
+		symbols, _ := loadFont("testdata/font_1")
+		var digits, others []*FontSymbol
+		for _, s := range symbols {
+			if s.symbol == "/" || s.symbol == "€" {
+				others = append(others, s)
+			} else {
+				digits = append(digits, s)
+			}
+		}
+		ocr.AddFontFamily("digits", digits...)
+		ocr.AddFontFamily("others", others...)
+		ocr.DebugFamilyPrefix = true
+
+		Convey("It prefixes each symbol with its family", func() {
+			text, err := ocr.Recognize(loadImageColor("testdata/test3.png"))
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "digits:3 digits:6 digits:6 digits:2\n"+
+				"digits:3 digits:2 others:€ others:/ others:€")
+		})
+	})
+}
+
+func TestOCRDelimited(t *testing.T) {
+	Convey("Given an OCR with delimited output", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		ocr.Delimited = true
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It encloses every symbol and space in delimiters", func() {
+			text, err := ocr.Recognize(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "|3|6|6|2|\n|3| |2|€|/|€|")
+		})
+
+		Convey("It keeps symbols of more than one rune together, escaping delimiters", func() {
+			ocr.ExpandLigatures = map[string]string{"6": "fi", "/": "|", "€": "\\"}
+			text, err := ocr.Recognize(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "|3|fi|fi|2|\n|3| |2|\\\\|\\||\\\\|")
+		})
+	})
+}
+
+func TestOCRExpectedGlyphs(t *testing.T) {
+	Convey("Given an OCR expecting a number of glyphs", t, func() {
+		// a single thread searches the symbols in order, so the search always stops at the same one
+		ocr := NewOCR(0.8, 1)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It recognizes the whole text when it has that many glyphs", func() {
+			ocr.ExpectedGlyphs = 9
+			text, err := ocr.Recognize(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It stops searching once enough glyphs were found", func() {
+			ocr.ExpectedGlyphs = 4
+			matches, err := ocr.RecognizeDetailed(img)
+			So(err, ShouldBeNil)
+			var symbols []string
+			for _, m := range matches {
+				symbols = append(symbols, m.Symbol)
+			}
+			So(symbols, ShouldResemble, []string{"2", "€", "/", "€"})
+		})
+	})
+}
+
+func TestOCRConfidenceWeights(t *testing.T) {
+	Convey("Given the matches of a text with a symbol scoring low", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		matches, _ := ocr.RecognizeDetailed(loadImageColor("testdata/test3.png"))
+
+		Convey("It lowers the confidence by default", func() {
+			So(ocr.Confidence(matches), ShouldBeBetween, 0.98, 0.99)
+		})
+
+		Convey("It ignores the symbols with a weight of 0", func() {
+			ocr.ConfidenceWeights = map[string]float64{"6": 0}
+			So(ocr.Confidence(matches), ShouldAlmostEqual, 1)
+		})
+
+		Convey("It has no confidence without matches", func() {
+			So(ocr.Confidence(nil), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestOCRSpaceTolerance(t *testing.T) {
+	Convey("Given an image with gaps of different widths", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It turns gaps narrower than the advance into spaces with a positive tolerance", func() {
+			ocr.SpaceTolerance = 4
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2 €/€")
+		})
+
+		Convey("It requires gaps wider than the advance with a negative tolerance", func() {
+			ocr.SpaceTolerance = -3
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n32€/€")
+		})
+	})
+}
+
+func TestOCRSpaceFactor(t *testing.T) {
+	Convey("Given an image with gaps of different widths", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It defaults to a factor of 1", func() {
+			So(ocr.SpaceFactor, ShouldEqual, 1)
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It turns narrower gaps into spaces with a factor below 1", func() {
+			ocr.SpaceFactor = 0.6
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2 €/€")
+		})
+
+		Convey("It requires wider gaps with a factor above 1", func() {
+			ocr.SpaceFactor = 1.3
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n32€/€")
+		})
+
+		Convey("It is combined with the tolerance", func() {
+			ocr.SpaceFactor = 1.3
+			ocr.SpaceTolerance = 1
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It takes zero as the default", func() {
+			ocr.SpaceFactor = 0
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+	})
+}
+
+func TestOCRCountGlyphs(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It counts the symbols in the image", func() {
+			count, err := ocr.CountGlyphs(loadImageColor("testdata/test3.png"))
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 9)
+		})
+
+		Convey("It counts no symbols in an image without text", func() {
+			count, err := ocr.CountGlyphs(newGrayImage(20, 20, nil))
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestOCRRecognizeDetailed(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It returns the same symbols Recognize writes, in the same order", func() {
+			text, _ := ocr.Recognize(img)
+			matches, err := ocr.RecognizeDetailed(img)
+			So(err, ShouldBeNil)
+			var symbols []string
+			for _, m := range matches {
+				symbols = append(symbols, m.Symbol)
+			}
+			So(strings.Join(symbols, ""), ShouldEqual, strings.Join(strings.Fields(text), ""))
+		})
+
+		Convey("It returns the area and score of each symbol", func() {
+			matches, _ := ocr.RecognizeDetailed(img)
+			So(matches[7].Symbol, ShouldEqual, "/")
+			So(matches[7].Rect, ShouldResemble, image.Rect(60, 27, 70, 41))
+			So(matches[7].G, ShouldAlmostEqual, 1)
+		})
+	})
+}
+
+func TestOCRRecognizeContext(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8, 4)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+		goroutines := runtime.NumGoroutine()
+		// the goroutines stopped may take a moment to exit after the recognition returns
+		settled := func() int {
+			for i := 0; i < 100 && runtime.NumGoroutine() > goroutines; i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			return runtime.NumGoroutine()
+		}
+
+		Convey("When the context is not done", func() {
+			text, err := ocr.RecognizeContext(context.Background(), img)
+
+			Convey("It recognizes the text", func() {
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			text, err := ocr.RecognizeContext(ctx, img)
+
+			Convey("It returns the error of the context", func() {
+				So(err, ShouldEqual, context.Canceled)
+				So(text, ShouldEqual, "")
+			})
+
+			Convey("It stops all the goroutines it started", func() {
+				So(settled(), ShouldBeLessThanOrEqualTo, goroutines)
+			})
+		})
+
+		Convey("When the search for many symbols fails", func() {
+			bi := newImageBinary(ensureGrayScale(img))
+			var symbols []*FontSymbol
+			for _, s := range ocr.allSymbols {
+				invalid := *s
+				invalid.image = newImageBinary(img) // color channels can't be matched to gray ones
+				symbols = append(symbols, &invalid)
+			}
+			_, err := newParallelFinder(context.Background(), 4, symbols, bi, 0.8, image.Rect(0, 0, bi.width-1, bi.height-1)).lookupAll()
+
+			Convey("It returns the error", func() {
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("It stops all the goroutines it started", func() {
+				So(settled(), ShouldBeLessThanOrEqualTo, goroutines)
+			})
+		})
+	})
+}
+
+func TestOCRStretch(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("And an image with condensed text", func() {
+			img := scale(loadImageGray("testdata/test3.png").(*image.Gray), 0.85, 1)
+
+			Convey("It does not recognize the text by default", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldNotEqual, "3662\n3 2€/€")
+			})
+
+			Convey("It recognizes the text when symbols are also searched condensed", func() {
+				ocr.StretchX = []float64{0.85}
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("It searches every combination of the factors of both axes", func() {
+			ocr.StretchX = []float64{0.9, 1, 1.1}
+			ocr.StretchY = []float64{0.9}
+			So(ocr.searchSymbols(ocr.allSymbols), ShouldHaveLength, 6*len(ocr.allSymbols))
+		})
+	})
+}
+
+func TestOCRScales(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("And an image with bigger text", func() {
+			img := scale(loadImageGray("testdata/test3.png").(*image.Gray), 1.2, 1.2)
+
+			Convey("It does not recognize the text by default", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldNotEqual, "3662\n3 2€/€")
+			})
+
+			Convey("It recognizes the text when symbols are also searched scaled", func() {
+				ocr.WithScales([]float64{0.9, 1.2})
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+
+				Convey("And the matches cover the scaled symbols", func() {
+					original, _ := ocr.RecognizeDetailed(loadImageGray("testdata/test3.png"))
+					matches, _ := ocr.RecognizeDetailed(img)
+					So(matches, ShouldHaveLength, len(original))
+					for i, m := range matches {
+						So(m.Rect.Dx(), ShouldBeGreaterThan, original[i].Rect.Dx())
+						So(m.Rect.Dy(), ShouldBeGreaterThan, original[i].Rect.Dy())
+					}
+				})
+			})
+		})
+
+		Convey("It combines the scales with the stretch factors", func() {
+			ocr.StretchX = []float64{0.9}
+			So(ocr.WithScales([]float64{1, 1.1}), ShouldPointTo, ocr)
+			So(ocr.Scales, ShouldResemble, []float64{1, 1.1})
+			ocr.Scales = []float64{1.1}
+			So(ocr.searchSymbols(ocr.allSymbols), ShouldHaveLength, 4*len(ocr.allSymbols))
+		})
+	})
+}
+
+func TestOCRSameLabelPolicy(t *testing.T) {
+	Convey("Given an OCR with two font families defining the same symbols", t, func() {
+		ocr := NewOCR(0.8)
+		a, _ := loadFont("testdata/font_1")
+		b, _ := loadFont("testdata/font_1")
+		ocr.AddFontFamily("a", a...)
+		ocr.AddFontFamily("b", b...)
+		ocr.DebugFamilyPrefix = true
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("When preferring a priority family", func() {
+			ocr.SameLabelPolicy = SameLabelPriorityFamily
+
+			Convey("It keeps the symbols of the first family in the priority", func() {
+				ocr.FamilyPriority = []string{"b", "a"}
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "b:3 b:6 b:6 b:2\nb:3 b:2 b:€ b:/ b:€")
+
+				ocr.FamilyPriority = []string{"a"}
+				text, _ = ocr.Recognize(img)
+				So(text, ShouldEqual, "a:3 a:6 a:6 a:2\na:3 a:2 a:€ a:/ a:€")
+			})
+
+			Convey("It keeps them regardless of the order the symbols were found in", func() {
+				ocr.FamilyPriority = []string{"b", "a"}
+				bi, _ := ocr.prepare(img)
+				found, _ := ocr.find(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), ocr.allSymbols, false)
+				for i := 0; i < 10; i++ {
+					shuffled := append([]*fontSymbolLookup{}, found...)
+					rand.Shuffle(len(shuffled), func(i, j int) {
+						shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+					})
+					So(ocr.arrange(bi, ocr.kept(bi, shuffled)), ShouldEqual, "b:3 b:6 b:6 b:2\nb:3 b:2 b:€ b:/ b:€")
+				}
+			})
+		})
+
+		Convey("When keeping alternatives", func() {
+			ocr.SameLabelPolicy = SameLabelKeepAlternatives
+			matches, _ := ocr.RecognizeDetailed(img)
+
+			Convey("It reports the symbol of the other family as an alternative", func() {
+				So(matches, ShouldHaveLength, 9)
+				for _, m := range matches {
+					var rects []image.Rectangle
+					for _, alt := range m.Alternatives {
+						So(alt.Symbol, ShouldEqual, m.Symbol)
+						rects = append(rects, alt.Rect)
+					}
+					So(rects, ShouldContain, m.Rect)
+				}
+			})
+		})
+
+		Convey("By default, it does not report alternatives", func() {
+			matches, _ := ocr.RecognizeDetailed(img)
+			So(matches[0].Alternatives, ShouldBeNil)
+		})
+	})
+}
+
+func TestOCRResourceLimits(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("When the image has more pixels than allowed", func() {
+			ocr.MaxImagePixels = 84*50 - 1
+
+			Convey("It returns an error", func() {
+				_, err := ocr.Recognize(img)
+				So(err, ShouldNotBeNil)
+				_, err = ocr.RecognizeGray(loadImageGray("testdata/test3.png").(*image.Gray))
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When the image has as many pixels as allowed", func() {
+			ocr.MaxImagePixels = 84 * 50
+
+			Convey("It recognizes the text", func() {
+				text, err := ocr.Recognize(img)
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When more candidates than allowed are found", func() {
+			ocr.MaxCandidates = 5
+
+			Convey("It returns an error", func() {
+				text, err := ocr.Recognize(img)
+				So(err, ShouldNotBeNil)
+				So(text, ShouldEqual, "")
+			})
+		})
+
+		Convey("When the candidates found are within the limit", func() {
+			ocr.MaxCandidates = 100
+
+			Convey("It recognizes the text", func() {
+				text, err := ocr.Recognize(img)
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When scoring every position finds more candidates than allowed", func() {
+			ocr.MaxCandidates = 100
+
+			Convey("It returns an error", func() {
+				_, err := ocr.ConfidenceMap(img)
+				So(err, ShouldNotBeNil)
+				ocr.SetThreshold(0.99)
+				_, err = ocr.RecognizeBestEffort(img)
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestOCRMinLineConfidence(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("When a line has a mean confidence below the minimum", func() {
+			// the first line has a '6' scoring 0.88, and the rest of the symbols score 1
+			ocr.MinLineConfidence = 0.98
+
+			Convey("It drops the whole line", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3 2€/€")
+				matches, _ := ocr.RecognizeDetailed(img)
+				So(matches, ShouldHaveLength, 5)
+			})
+
+			Convey("It keeps the line when using the median confidence", func() {
+				ocr.LineConfidenceMedian = true
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When all lines are above the minimum", func() {
+			ocr.MinLineConfidence = 0.9
+
+			Convey("It keeps all of them", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+	})
+}
+
+func TestOCRRecognizeLayout(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		// write builds the text from the glyphs, like Recognize does
+		write := func(ocr *OCR, glyphs []PlacedGlyph) string {
+			var str strings.Builder
+			for _, g := range glyphs {
+				if g.NewLine {
+					str.WriteString(strings.Repeat("\n", g.BlankLines+1))
+				} else if g.Unknown {
+					str.WriteString(ocr.UnknownGlyph)
+				}
+				str.WriteString(strings.Repeat(" ", g.Spaces) + g.Text)
+			}
+			return str.String()
+		}
+
+		Convey("It returns the glyphs in the order of the text", func() {
+			glyphs, err := ocr.RecognizeLayout(img)
+			So(err, ShouldBeNil)
+			So(glyphs, ShouldHaveLength, 9)
+			So(write(ocr, glyphs), ShouldEqual, "3662\n3 2€/€")
+			So(glyphs[4].NewLine, ShouldBeTrue)
+			So(glyphs[5].Spaces, ShouldEqual, 1)
+			So(glyphs[4].Rect, ShouldResemble, image.Rect(12, 27, 21, 41))
+		})
+
+		Convey("It keeps the order of right-to-left text", func() {
+			ocr.SetFamilyRTL("font_1", true)
+			glyphs, _ := ocr.RecognizeLayout(img)
+			text, _ := ocr.Recognize(img)
+			So(write(ocr, glyphs), ShouldEqual, text)
+			So(glyphs[0].Symbol, ShouldEqual, "2")
+		})
+
+		Convey("It flags the glyphs after ink no symbol matched", func() {
+			missing := NewOCR(0.8)
+			for _, s := range ocr.allSymbols {
+				if s.symbol != "/" {
+					missing.AddSymbols(s)
+				}
+			}
+			missing.UnknownGlyph = "\uFFFD"
+			glyphs, err := missing.RecognizeLayout(img)
+			So(err, ShouldBeNil)
+			So(write(missing, glyphs), ShouldEqual, "3662\n3 2€\uFFFD €")
+			So(glyphs[7].Unknown, ShouldBeTrue)
+		})
+
+		Convey("It has the glyphs of the lines recognized by the Fallback", func() {
+			missing := NewOCR(0.6)
+			for _, s := range ocr.allSymbols {
+				if s.symbol != "€" {
+					missing.AddSymbols(s)
+				}
+			}
+			missing.Fallback = ocr
+			missing.FallbackConfidence = 0.95
+			glyphs, err := missing.RecognizeLayout(img)
+			So(err, ShouldBeNil)
+			text, _ := missing.Recognize(img)
+			So(write(missing, glyphs), ShouldEqual, text)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			So(glyphs[6].Symbol, ShouldEqual, "€")
+
+			empty := NewOCR(0.8)
+			empty.Fallback = ocr
+			glyphs, err = empty.RecognizeLayout(img)
+			So(err, ShouldBeNil)
+			So(write(empty, glyphs), ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It returns the same glyphs when the text is delimited", func() {
+			expected, _ := ocr.RecognizeLayout(img)
+			ocr.Delimited = true
+			glyphs, _ := ocr.RecognizeLayout(img)
+			So(glyphs, ShouldResemble, expected)
+		})
+	})
+}
+
+func TestOCRRightToLeft(t *testing.T) {
+	Convey("Given an OCR with some symbols of a right-to-left script", t, func() {
+		ocr := NewOCR(0.8)
+		symbols, _ := loadFont("testdata/font_1")
+		var rtl, ltr []*FontSymbol
+		for _, s := range symbols {
+			if s.symbol == "2" || s.symbol == "3" || s.symbol == "6" {
+				rtl = append(rtl, s)
+			} else {
+				ltr = append(ltr, s)
+			}
+		}
+		ocr.AddFontFamily("rtl", rtl...)
+		ocr.AddFontFamily("ltr", ltr...)
+		ocr.SetFamilyRTL("rtl", true)
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It writes the runs of right-to-left symbols in reverse order", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "2663\n2 3€/€")
+		})
+
+		Convey("It keeps the order of the symbols found", func() {
+			matches, _ := ocr.RecognizeDetailed(img)
+			So(matches[0].Symbol, ShouldEqual, "3")
+		})
+
+		Convey("It writes them in order again when unmarked", func() {
+			ocr.SetFamilyRTL("rtl", false)
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+
+		Convey("It doesn't change the symbols, shared with other OCRs", func() {
+			So(rtl[0].RTL(), ShouldBeFalse)
+			other := NewOCR(0.8)
+			other.AddFontFamily("rtl", rtl...)
+			other.AddFontFamily("ltr", ltr...)
+			text, _ := other.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			clone := ocr.Clone()
+			clone.SetFamilyRTL("rtl", false)
+			text, _ = ocr.Recognize(img)
+			So(text, ShouldEqual, "2663\n2 3€/€")
+		})
+	})
+
+	Convey("Given a symbol created as right-to-left", t, func() {
+		fs := NewFontSymbolOpts("3", loadImageGray("testdata/font_1/3.png"), &NewFontSymbolOptions{RTL: true})
+
+		Convey("It is marked as right-to-left", func() {
+			So(fs.RTL(), ShouldBeTrue)
+			fs.SetRTL(false)
+			So(fs.RTL(), ShouldBeFalse)
+		})
+	})
+}
+
+func TestOCRAddFontFamilyUnique(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("When the same font is added again", func() {
+			symbols, _ := loadFont("testdata/font_1")
+			added := ocr.AddFontFamilyUnique("font_1", symbols...)
+
+			Convey("It skips all its symbols", func() {
+				So(added, ShouldEqual, 0)
+				So(ocr.allSymbols, ShouldHaveLength, 13)
+				So(ocr.fontFamilies["font_1"], ShouldHaveLength, 13)
+			})
+		})
+
+		Convey("When a symbol with the same image but another label is added", func() {
+			zero := NewFontSymbol("O", loadImageGray("testdata/font_1/0.png"))
+			added := ocr.AddFontFamilyUnique("font_1", zero, zero)
+
+			Convey("It adds it only once", func() {
+				So(added, ShouldEqual, 1)
+				So(ocr.fontFamilies["font_1"], ShouldHaveLength, 14)
+			})
+		})
+
+		Convey("When the same font is added to another family", func() {
+			symbols, _ := loadFont("testdata/font_1")
+			added := ocr.AddFontFamilyUnique("copy", symbols...)
+
+			Convey("It adds all its symbols", func() {
+				So(added, ShouldEqual, 13)
+				So(ocr.allSymbols, ShouldHaveLength, 26)
+			})
+		})
+	})
+}
+
+func TestOCRImageBands(t *testing.T) {
+	Convey("Given an OCR with a loaded font, searching the image in bands", t, func() {
+		ocr := NewOCR(0.8, 4)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+		expected, _ := ocr.RecognizeDetailed(img)
+
+		for _, bands := range []int{2, 3, 7, 50} {
+			Convey(fmt.Sprintf("It finds the same symbols as without bands, using %d bands", bands), func() {
+				ocr.ImageBands = bands
+				ocr.Deterministic = true
+				matches, err := ocr.RecognizeDetailed(img)
+				So(err, ShouldBeNil)
+				So(matches, ShouldResemble, expected)
+			})
+		}
+
+		Convey("It finds every candidate exactly once", func() {
+			bi := newImageBinary(ensureGrayScale(img))
+			rect := image.Rect(0, 0, bi.width-1, bi.height-1)
+			all, _ := newParallelFinder(context.Background(), 4, ocr.allSymbols, bi, 0.5, rect).lookupAll()
+			f := newParallelFinder(context.Background(), 4, ocr.allSymbols, bi, 0.5, rect)
+			f.bands = 5
+			banded, _ := f.lookupAll()
+			key := func(l *fontSymbolLookup) string { return fmt.Sprintf("%p %d %d", l.fs, l.x, l.y) }
+			seen := map[string]int{}
+			for _, l := range banded {
+				seen[key(l)]++
+			}
+			So(banded, ShouldHaveLength, len(all))
+			for _, l := range all {
+				So(seen[key(l)], ShouldEqual, 1)
+			}
+		})
+	})
+}
+
+func TestOCRLineSegmentation(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		ocr.Deterministic = true
+		test3 := loadImageGray("testdata/test3.png").(*image.Gray)
+		first, second := image.Rect(6, 4, 48, 19), image.Rect(12, 25, 80, 42)
+
+		// relayout draws the two lines of text of test3 at the given positions
+		relayout := func(at1, at2 image.Point) *image.Gray {
+			img := image.NewGray(image.Rect(0, 0, 160, 120))
+			draw.Draw(img, img.Bounds(), image.NewUniform(color.Gray{Y: 46}), image.Point{}, draw.Src)
+			draw.Draw(img, first.Sub(first.Min).Add(at1), test3, first.Min, draw.Src)
+			draw.Draw(img, second.Sub(second.Min).Add(at2), test3, second.Min, draw.Src)
+			return img
+		}
+
+		Convey("And a line wrapping to the right of the previous one", func() {
+			img := relayout(image.Pt(4, 4), image.Pt(70, 24))
+
+			Convey("It does not break the line by default", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldNotContainSubstring, "\n")
+			})
+
+			Convey("It breaks the line when segmenting lines", func() {
+				ocr.LineSegmentation = true
+				text, err := ocr.Recognize(img)
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("And lines far apart when segmenting lines", func() {
+			ocr.LineSegmentation = true
+
+			Convey("It writes an empty line for each line height between them", func() {
+				text, _ := ocr.Recognize(relayout(image.Pt(4, 4), image.Pt(4, 40)))
+				So(text, ShouldEqual, "3662\n\n3 2€/€")
+			})
+
+			Convey("It writes no empty lines for lines close to each other", func() {
+				text, _ := ocr.Recognize(test3)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+	})
+}
+
+func TestOCROverlapRatio(t *testing.T) {
+	Convey("Given a big symbol partially covering a small one", t, func() {
+		letter := NewFontSymbol("a", image.NewGray(image.Rect(0, 0, 12, 16)))
+		accent := NewFontSymbol("¨", image.NewGray(image.Rect(0, 0, 4, 3)))
+		found := func() []*fontSymbolLookup {
+			return []*fontSymbolLookup{
+				newFontSymbolLookup(letter, 0, 4, 0.9),
+				newFontSymbolLookup(accent, 10, 5, 0.9),
+			}
+		}
+		ocr := NewOCR(0.8)
+
+		Convey("It removes the small one by default", func() {
+			So(ocr.dedup(found()), ShouldHaveLength, 1)
+		})
+
+		Convey("It removes the small one when covered more than the ratio", func() {
+			ocr.OverlapRatio = 0.3
+			So(ocr.dedup(found()), ShouldHaveLength, 1)
+		})
+
+		Convey("It keeps the small one when covered less than the ratio", func() {
+			ocr.OverlapRatio = 0.6
+			So(ocr.dedup(found()), ShouldHaveLength, 2)
+		})
+
+		Convey("It keeps both in threshold steps too", func() {
+			ocr.OverlapRatio = 0.6
+			ocr.ThresholdSteps = []float64{0.95, 0.85}
+			So(ocr.dedup(found()), ShouldHaveLength, 2)
+		})
+
+		Convey("It keeps symbols fully covered with a ratio of 1", func() {
+			ocr.OverlapRatio = 1
+			all := append(found(), newFontSymbolLookup(accent, 4, 6, 0.9))
+			So(ocr.dedup(all), ShouldHaveLength, 3)
+		})
+	})
+}
+
+func TestOCRRecognizeWhitelist(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It only recognizes the allowed symbols", func() {
+			text, err := ocr.RecognizeWhitelist(img, "0123456789")
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2")
+		})
+
+		Convey("It does not recognize the denied symbols", func() {
+			text, err := ocr.RecognizeBlacklist(img, "€6")
+			So(err, ShouldBeNil)
+			So(text, ShouldNotContainSubstring, "€")
+			So(text, ShouldNotContainSubstring, "6")
+			So(text, ShouldContainSubstring, "/")
+		})
+
+		Convey("It recognizes everything with an empty blacklist", func() {
+			text, _ := ocr.RecognizeBlacklist(img, "")
+			So(text, ShouldEqual, "3662\n3 2€/€")
+		})
+	})
+}
+
+func TestOCRNoSymbols(t *testing.T) {
+	Convey("Given an OCR without any font loaded", t, func() {
+		ocr := NewOCR(0.8)
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It fails to recognize the text", func() {
+			_, err := ocr.Recognize(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = ocr.RecognizeDetailed(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, _, err = ocr.RecognizeTimed(img)
+			So(err, ShouldEqual, ErrNoSymbols)
+			_, err = ocr.RecognizeProgress(img, nil)
+			So(err, ShouldEqual, ErrNoSymbols)
+		})
+	})
+
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It fails when no symbol is allowed", func() {
+			_, err := ocr.RecognizeWhitelist(loadImageColor("testdata/test3.png"), "abc")
+			So(err, ShouldEqual, ErrNoSymbols)
+		})
+
+		Convey("It recognizes no text in a blank image without failing", func() {
+			text, err := ocr.Recognize(image.NewGray(image.Rect(0, 0, 40, 30)))
+			So(err, ShouldBeNil)
+			So(text, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestOCRCertainty(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8, 4)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+		_, stats, _ := ocr.RecognizeStats(img)
+
+		Convey("It recognizes the same text, with less candidates, skipping the certain areas", func() {
+			ocr.Certainty = 0.98
+			text, certain, err := ocr.RecognizeStats(img)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+			So(certain.RawMatches, ShouldBeLessThan, stats.RawMatches)
+			So(certain.FinalMatches, ShouldEqual, stats.FinalMatches)
+		})
+
+		Convey("It skips the certain areas with a custom matcher too", func() {
+			ocr.Certainty = 0.98
+			certain := &countingMatcher{Matcher: NCCMatcher{}}
+			ocr.Matcher = certain
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€/€")
+
+			ocr.Certainty = 0
+			all := &countingMatcher{Matcher: NCCMatcher{}}
+			ocr.Matcher = all
+			_, _ = ocr.Recognize(img)
+			So(certain.calls, ShouldBeLessThan, all.calls)
+		})
+
+		Convey("It finds the same candidates whatever the threads and bands finish first", func() {
+			// below the threshold of 0.8, the certain areas keep candidates of other symbols from
+			// being found, which would depend on the symbols settled first
+			expected := NewOCR(0.7, 1)
+			expected.AddFontFamily("font_1", ocr.allSymbols...)
+			expected.Certainty = 0.9
+			matches, _ := expected.RecognizeDetailed(img)
+			_, stats, _ := expected.RecognizeStats(img)
+			for _, threads := range []int{2, 4, 8} {
+				for _, bands := range []int{0, 3} {
+					other := NewOCR(0.7, threads)
+					other.AddFontFamily("font_1", ocr.allSymbols...)
+					other.Certainty = 0.9
+					other.ImageBands = bands
+					for i := 0; i < 5; i++ {
+						found, err := other.RecognizeDetailed(img)
+						So(err, ShouldBeNil)
+						So(found, ShouldResemble, matches)
+						if bands == 0 {
+							_, otherStats, _ := other.RecognizeStats(img)
+							So(otherStats.RawMatches, ShouldEqual, stats.RawMatches)
+						}
+					}
+				}
+			}
+		})
+	})
+}
+
+func TestOCRClone(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8, 2)
+		_ = ocr.LoadFont("testdata/font_1")
+		ocr.SetFamilyDPI("font_1", 96)
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("When I clone it", func() {
+			clone := ocr.Clone()
+
+			Convey("It recognizes the same text with the same symbols", func() {
+				text, err := clone.Recognize(img)
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+				So(clone.allSymbols[0], ShouldPointTo, ocr.allSymbols[0])
+			})
+
+			Convey("Changing the clone does not affect the original", func() {
+				clone.SetThreshold(0.95)
+				clone.SetThreads(8)
+				clone.AddSymbols(NewFontSymbol("x", image.NewGray(image.Rect(0, 0, 4, 4))))
+				clone.AddFontFamily("font_1", NewFontSymbol("y", image.NewGray(image.Rect(0, 0, 4, 4))))
+				clone.AddFontFamily("other", NewFontSymbol("z", image.NewGray(image.Rect(0, 0, 4, 4))))
+				clone.SetFamilyDPI("font_1", 300)
+
+				So(ocr.threshold, ShouldEqual, 0.8)
+				So(ocr.numThreads, ShouldEqual, 2)
+				So(ocr.allSymbols, ShouldHaveLength, 13)
+				So(ocr.fontFamilies, ShouldHaveLength, 1)
+				So(ocr.fontFamilies["font_1"], ShouldHaveLength, 13)
+				So(ocr.familyDPI["font_1"], ShouldEqual, 96)
+
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+				text, _ = clone.Recognize(img)
+				So(text, ShouldNotEqual, "3662\n3 2€/€")
+				So(clone.numThreads, ShouldEqual, 8)
+			})
+
+			Convey("Changing the families of the shared symbols in the clone does not affect the original", func() {
+				clone.AddFontFamily("x", ocr.allSymbols...)
+				clone.SetFamilyRTL("x", true)
+
+				matches, _ := ocr.RecognizeDetailed(img)
+				for _, m := range matches {
+					So(m.Family, ShouldEqual, "font_1")
+				}
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+				matches, _ = clone.RecognizeDetailed(img)
+				So(matches[0].Family, ShouldEqual, "x")
+				text, _ = clone.Recognize(img)
+				So(text, ShouldEqual, "2663\n€/€2 3")
+			})
+
+			Convey("Changing the original does not affect the clone", func() {
+				ocr.AddSymbols(NewFontSymbol("x", image.NewGray(image.Rect(0, 0, 4, 4))))
+				So(clone.allSymbols, ShouldHaveLength, 13)
+			})
+		})
+
+		Convey("It uses at least one thread", func() {
+			ocr.SetThreads(0)
+			So(ocr.numThreads, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestOCRRemoveFontFamily(t *testing.T) {
+	Convey("Given an OCR with two font families and a loose symbol", t, func() {
+		ocr := NewOCR(0.8, 2)
+		_ = ocr.LoadFont("testdata/font_1")
+		ocr.SetFamilyDPI("font_1", 96)
+		other := NewFontSymbol("y", image.NewGray(image.Rect(0, 0, 4, 4)))
+		ocr.AddFontFamily("other", other)
+		loose := NewFontSymbol("x", image.NewGray(image.Rect(0, 0, 4, 4)))
+		ocr.AddSymbols(loose)
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("When I remove one of the families", func() {
+			clone := ocr.Clone()
+			ocr.RemoveFontFamily("font_1")
+
+			Convey("Only the symbols of the other family and the loose ones are left", func() {
+				So(ocr.allSymbols, ShouldResemble, []*FontSymbol{other, loose})
+				So(ocr.fontFamilies, ShouldNotContainKey, "font_1")
+				So(ocr.familyDPI, ShouldNotContainKey, "font_1")
+				text, err := ocr.Recognize(img)
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "")
+			})
+
+			Convey("A clone made before keeps all of them", func() {
+				So(clone.allSymbols, ShouldHaveLength, 15)
+				text, _ := clone.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+
+			Convey("It can be loaded again", func() {
+				_ = ocr.LoadFont("testdata/font_1")
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("Removing an unknown family does nothing", func() {
+			ocr.RemoveFontFamily("unknown")
+			So(ocr.allSymbols, ShouldHaveLength, 15)
+		})
+	})
+
+	Convey("Given an OCR with the same symbols in two families, some also added loose", t, func() {
+		ocr := NewOCR(0.8)
+		symbols, _ := loadFont("testdata/font_1")
+		ocr.AddFontFamily("A", symbols...)
+		ocr.AddFontFamily("B", symbols...)
+		ocr.AddSymbols(symbols[0])
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("When I remove one of the families", func() {
+			ocr.RemoveFontFamily("A")
+
+			Convey("The symbols of the other family, and the loose one, are kept", func() {
+				So(ocr.allSymbols, ShouldResemble, append(append([]*FontSymbol{}, symbols...), symbols[0]))
+				So(ocr.fontFamilies["B"], ShouldHaveLength, 13)
+				So(ocr.looseSymbols, ShouldResemble, []*FontSymbol{symbols[0]})
+				matches, err := ocr.RecognizeDetailed(img)
+				So(err, ShouldBeNil)
+				So(matches, ShouldHaveLength, 9)
+				for _, m := range matches {
+					So(m.Family, ShouldEqual, "B")
+				}
+			})
+
+			Convey("Removing the other family leaves only the loose symbol", func() {
+				ocr.RemoveFontFamily("B")
+				So(ocr.allSymbols, ShouldResemble, []*FontSymbol{symbols[0]})
+				So(ocr.symbolFamily, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+// stallingMatcher scores like the NCCMatcher, but stalls the first time it scores a symbol of the
+// given height, closing stalled and waiting until release is closed
+type stallingMatcher struct {
+	NCCMatcher
+	height  int
+	stalled chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (m *stallingMatcher) Score(symbol, img *image.Gray, x, y int) float64 {
+	if symbol.Bounds().Dy() == m.height {
+		m.once.Do(func() {
+			close(m.stalled)
+			<-m.release
+		})
+	}
+	return m.NCCMatcher.Score(symbol, img, x, y)
+}
+
+func TestOCRRecognizeTimeout(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		ocr := NewOCR(0.8, 1)
+		_ = ocr.LoadFont("testdata/font_1")
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("When the recognition finishes in time", func() {
+			text, err := ocr.RecognizeTimeout(img, time.Minute)
+
+			Convey("It recognizes the text", func() {
+				So(err, ShouldBeNil)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When the time is up before the search starts", func() {
+			text, err := ocr.RecognizeTimeout(img, 0)
+
+			Convey("It returns the timeout without any text", func() {
+				So(err, ShouldEqual, context.DeadlineExceeded)
+				So(text, ShouldEqual, "")
+			})
+		})
+
+		Convey("When the time is up while searching for the last symbol", func() {
+			ocr.AddSymbols(NewFontSymbol("x", image.NewGray(image.Rect(0, 0, 3, 40))))
+			matcher := &stallingMatcher{height: 40, stalled: make(chan struct{}), release: make(chan struct{})}
+			ocr.Matcher = matcher
+			timeout := 50 * time.Millisecond
+			go func() {
+				// the timeout started before the search, so it is up once it passes after the stall
+				<-matcher.stalled
+				<-time.After(timeout)
+				close(matcher.release)
+			}()
+			text, err := ocr.RecognizeTimeout(img, timeout)
+
+			Convey("It returns the text of the symbols found until then with the timeout", func() {
+				So(err, ShouldEqual, context.DeadlineExceeded)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When there are no symbols", func() {
+			_, err := NewOCR(0.8, 1).RecognizeTimeout(img, time.Minute)
+
+			Convey("It fails", func() {
+				So(err, ShouldEqual, ErrNoSymbols)
+			})
+		})
+	})
+}
+
+func TestOCRUnknownGlyph(t *testing.T) {
+	Convey("Given an OCR with a fontset missing a symbol present in the image", t, func() {
+		ocr := NewOCR(0.8)
+		symbols, _ := loadFont("testdata/font_1")
+		for _, s := range symbols {
+			if s.symbol != "/" {
+				ocr.AddSymbols(s)
+			}
+		}
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It silently omits the missing symbol by default", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "3662\n3 2€ €")
+		})
+
+		Convey("When UnknownGlyph is set", func() {
+			ocr.UnknownGlyph = "\uFFFD"
+
+			Convey("It marks the gap containing the missing symbol", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€\uFFFD €")
+			})
+		})
+	})
+
+	Convey("Given an OCR with a fontset missing a symbol in a gap wide enough for a space", t, func() {
+		ocr := NewOCR(0.8)
+		symbols, _ := loadFont("testdata/font_1")
+		for _, s := range symbols {
+			if s.symbol != "2" {
+				ocr.AddSymbols(s)
+			}
+		}
+		ocr.UnknownGlyph = "\uFFFD"
+		img := loadImageColor("testdata/test3.png")
+
+		Convey("It writes both the glyph and the space", func() {
+			text, _ := ocr.Recognize(img)
+			So(text, ShouldEqual, "366\n3\uFFFD €/€")
+		})
+	})
+}
+
+func TestOCRDeterministic(t *testing.T) {
+	Convey("Given a deterministic OCR", t, func() {
+		ocr := NewOCR(0.7)
+		ocr.Deterministic = true
+		_ = ocr.LoadFont("testdata/font_1")
+		bi := newImageBinary(loadImageGray("testdata/test3.png"))
+		found, _ := ocr.find(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), ocr.allSymbols, false)
+
+		Convey("It produces the same output regardless of the order of the candidates", func() {
+			expected := ocr.arrange(bi, ocr.kept(bi, append([]*fontSymbolLookup{}, found...)))
+			for i := 0; i < 10; i++ {
+				shuffled := append([]*fontSymbolLookup{}, found...)
+				rand.Shuffle(len(shuffled), func(i, j int) {
+					shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+				})
+				So(ocr.arrange(bi, ocr.kept(bi, shuffled)), ShouldEqual, expected)
+			}
+			So(expected, ShouldEqual, "3662\n3 2€/€")
+		})
+	})
+}
+
+func TestOCRMatchFamily(t *testing.T) {
+	Convey("Given an OCR with the digits and the other symbols in different families", t, func() {
+		ocr := NewOCR(0.8)
+		symbols, _ := loadFont("testdata/font_1")
+		var digits, others []*FontSymbol
+		for _, s := range symbols {
+			if s.symbol >= "0" && s.symbol <= "9" {
+				digits = append(digits, s)
+			} else {
+				others = append(others, s)
+			}
+		}
+		ocr.AddFontFamily("digits", digits...)
+		ocr.AddFontFamily("others", others...)
+
+		Convey("When I recognize an image", func() {
+			matches, err := ocr.RecognizeDetailed(loadImageColor("testdata/test3.png"))
+			So(err, ShouldBeNil)
+
+			Convey("It reports the family of each matched symbol", func() {
+				So(matches, ShouldHaveLength, 9)
+				for _, m := range matches {
+					if m.Symbol >= "0" && m.Symbol <= "9" {
+						So(m.Family, ShouldEqual, "digits")
+					} else {
+						So(m.Family, ShouldEqual, "others")
+					}
+				}
+			})
+		})
+
+		Convey("The symbols themselves are not changed", func() {
+			So(digits[0].Family(), ShouldEqual, "")
+		})
+
+		Convey("Symbols shared with another OCR keep the family of each OCR", func() {
+			other := NewOCR(0.8)
+			other.AddFontFamily("shared", symbols...)
+			matches, _ := ocr.RecognizeDetailed(loadImageColor("testdata/test3.png"))
+			So(matches[0].Family, ShouldEqual, "digits")
+			matches, _ = other.RecognizeDetailed(loadImageColor("testdata/test3.png"))
+			for _, m := range matches {
+				So(m.Family, ShouldEqual, "shared")
+			}
+		})
+	})
+}
+
+func TestOCRFontWeights(t *testing.T) {
+	Convey("Given an OCR with regular and bold symbols in the same family", t, func() {
+		ocr := NewOCR(0.8)
+		symbols, _ := loadFont("testdata/font_1")
+		for _, s := range symbols {
+			if s.symbol == "3" {
+				s.weight = FontWeightBold
+			}
+		}
+		ocr.AddFontFamily("font_1", symbols...)
+
+		Convey("It keeps a single family", func() {
+			So(ocr.fontFamilies, ShouldHaveLength, 1)
+		})
+
+		Convey("When I recognize an image", func() {
+			matches, err := ocr.RecognizeDetailed(loadImageColor("testdata/test3.png"))
+			So(err, ShouldBeNil)
+
+			Convey("It reports the weight of each matched symbol", func() {
+				So(matches, ShouldHaveLength, 9)
+				for _, m := range matches {
+					if m.Symbol == "3" {
+						So(m.Weight, ShouldEqual, FontWeightBold)
+					} else {
+						So(m.Weight, ShouldEqual, FontWeightRegular)
+					}
+				}
+			})
+		})
+	})
+
+	Convey("When I create a symbol with options that only set the weight", t, func() {
+		fs := NewFontSymbolOpts("0", loadImageGray("testdata/font_1/0.png"), &NewFontSymbolOptions{Weight: FontWeightBold})
+
+		Convey("It keeps the default advance", func() {
+			So(fs.Advance(), ShouldEqual, fs.width)
+			So(fs.Weight(), ShouldEqual, FontWeightBold)
+		})
+	})
+}
+
+func TestFontSymbolAdvance(t *testing.T) {
+	Convey("Given a narrow symbol written twice, with a gap as wide as the symbol", t, func() {
+		glyph := loadImageGray("testdata/font_1/1.png").(*image.Gray)
+		w, h := glyph.Bounds().Dx(), glyph.Bounds().Dy()
+		img := image.NewGray(image.Rect(0, 0, 4*w+4, h+4))
+		draw.Draw(img, img.Bounds(), image.NewUniform(glyph.GrayAt(0, 0)), image.Point{}, draw.Src)
+		draw.Draw(img, image.Rect(2, 2, 2+w, 2+h), glyph, image.Point{}, draw.Src)
+		draw.Draw(img, image.Rect(2+2*w, 2, 2+3*w, 2+h), glyph, image.Point{}, draw.Src)
+
+		Convey("When the symbol advances its width", func() {
+			ocr := NewOCR(0.9)
+			ocr.AddSymbols(NewFontSymbol("1", glyph))
+
+			Convey("It takes the gap as a space", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "1 1")
+			})
+		})
+
+		Convey("When the symbol is created advancing further than its width", func() {
+			ocr := NewOCR(0.9)
+			ocr.AddSymbols(NewFontSymbolWithMetrics("1", glyph, 2*w))
+
+			Convey("It does not take the gap as a space", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "11")
+			})
+		})
+
+		Convey("When the advance is changed after creating the symbol", func() {
+			fs := NewFontSymbol("1", glyph)
+			fs.SetAdvance(2 * w)
+			ocr := NewOCR(0.9)
+			ocr.AddSymbols(fs)
+
+			Convey("It uses the new advance", func() {
+				So(fs.Advance(), ShouldEqual, 2*w)
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "11")
+			})
+
+			Convey("It advances its width again when set to zero", func() {
+				fs.SetAdvance(0)
+				So(fs.Advance(), ShouldEqual, w)
+			})
+		})
+
+		Convey("When the symbol is created with an advance of zero", func() {
+			fs := NewFontSymbolWithMetrics("1", glyph, 0)
+			ocr := NewOCR(0.9)
+			ocr.AddSymbols(fs)
+
+			Convey("It advances its width, like SetAdvance(0), instead of not advancing at all", func() {
+				So(fs.Advance(), ShouldEqual, w)
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "1 1")
+			})
+		})
+	})
+}
+
+func TestFontSymbolMinScore(t *testing.T) {
+	Convey("Given an OCR with a loaded font", t, func() {
+		img := loadImageColor("testdata/test3.png")
+		six := func(ocr *OCR) *FontSymbol {
+			for _, s := range ocr.allSymbols {
+				if s.symbol == "6" {
+					return s
+				}
+			}
+			return nil
+		}
+
+		Convey("When a symbol requires a higher score than the threshold", func() {
+			ocr := NewOCR(0.8)
+			_ = ocr.LoadFont("testdata/font_1")
+			six(ocr).SetMinScore(0.9)
+
+			Convey("It is not recognized below its own minimum score", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3 62\n3 2€/€")
+			})
+		})
+
+		Convey("When a symbol accepts a lower score than the threshold", func() {
+			ocr := NewOCR(0.9)
+			_ = ocr.LoadFont("testdata/font_1")
+			six(ocr).SetMinScore(0.85)
+
+			Convey("It is recognized with its own minimum score", func() {
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+
+		Convey("When the minimum score is reset", func() {
+			ocr := NewOCR(0.8)
+			_ = ocr.LoadFont("testdata/font_1")
+			six(ocr).SetMinScore(0.9)
+			six(ocr).SetMinScore(0)
+
+			Convey("It uses the threshold of the OCR again", func() {
+				So(six(ocr).MinScore(), ShouldEqual, 0)
+				text, _ := ocr.Recognize(img)
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+		})
+	})
+}
+
 func BenchmarkOCR(b *testing.B) {
 	b.StopTimer()
 	ocr := NewOCR(0.7)
@@ -0,0 +1,66 @@
+package lookup
+
+// resolveTransitions picks, for every symbol kept, the candidate of its slot that maximizes the
+// score of the whole text, taking into account the TransitionCost between adjacent symbols on
+// the same line. The alternatives of a slot are the discarded candidates overlapping only it.
+func (o *OCR) resolveTransitions(kept, candidates []*fontSymbolLookup) []*fontSymbolLookup {
+	if len(kept) < 2 {
+		return kept
+	}
+
+	// every kept symbol is a slot, with the candidates that could be placed there
+	slots := make([][]*fontSymbolLookup, len(kept))
+	for i, k := range kept {
+		index := map[string]int{k.fs.symbol: 0}
+		slots[i] = []*fontSymbolLookup{k}
+		for _, c := range candidates {
+			if !c.cross(k) || (i > 0 && c.cross(kept[i-1])) || (i < len(kept)-1 && c.cross(kept[i+1])) {
+				continue
+			}
+			// keep only the best candidate of each symbol, besides the one already kept
+			if j, ok := index[c.fs.symbol]; !ok {
+				index[c.fs.symbol] = len(slots[i])
+				slots[i] = append(slots[i], c)
+			} else if j > 0 && c.score > slots[i][j].score {
+				slots[i][j] = c
+			}
+		}
+	}
+
+	// Viterbi: total[i][j] is the best score of the text ending with candidate j of slot i
+	total := make([][]float64, len(slots))
+	from := make([][]int, len(slots))
+	for i, s := range slots {
+		total[i] = make([]float64, len(s))
+		from[i] = make([]int, len(s))
+		for j, c := range s {
+			if i == 0 {
+				total[i][j] = c.score
+				continue
+			}
+			for p, prev := range slots[i-1] {
+				t := total[i-1][p] + c.score
+				if c.x >= kept[i-1].x {
+					t -= o.TransitionCost(prev.fs.symbol, c.fs.symbol)
+				}
+				if p == 0 || t > total[i][j] {
+					total[i][j], from[i][j] = t, p
+				}
+			}
+		}
+	}
+
+	last := len(slots) - 1
+	j := 0
+	for c := range total[last] {
+		if total[last][c] > total[last][j] {
+			j = c
+		}
+	}
+	resolved := make([]*fontSymbolLookup, len(slots))
+	for i := last; i >= 0; i-- {
+		resolved[i] = slots[i][j]
+		j = from[i][j]
+	}
+	return resolved
+}
@@ -0,0 +1,130 @@
+package lookup
+
+import (
+	"context"
+	"image"
+	"sync"
+)
+
+// PreparedImage is an image to be queried repeatedly, with different symbols or regions. The
+// summed-area tables (integral images) all queries need are built only once, by BuildIndex or
+// lazily by the first query. It is safe for concurrent use.
+type PreparedImage struct {
+	img   image.Image
+	once  sync.Once
+	index *imageBinary
+
+	// normalized are the indexes of the image normalized with the ContrastTile, BinaryThreshold
+	// and AlphaOnly of the OCRs that recognized it, built once for each normalization
+	mu         sync.Mutex
+	normalized map[normalization]*imageBinary
+}
+
+// normalization are the options of an OCR that change the image before recognizing it
+type normalization struct {
+	contrastTile, binaryThreshold int
+	alphaOnly                     bool
+}
+
+// NewPreparedImage creates a PreparedImage for the image, which is converted to gray scale
+func NewPreparedImage(img image.Image) *PreparedImage {
+	return &PreparedImage{img: img}
+}
+
+// BuildIndex builds the summed-area tables of the image, if not already built. Call it upfront
+// to avoid the first query paying for it.
+func (p *PreparedImage) BuildIndex() {
+	p.once.Do(func() {
+		p.index = newImageBinary(ensureGrayScale(p.img))
+		p.index.offset = p.img.Bounds().Min
+	})
+}
+
+func (p *PreparedImage) binary() *imageBinary {
+	p.BuildIndex()
+	return p.index
+}
+
+// binaryFor returns the index of the image normalized like the OCR does before recognizing it,
+// so recognizing a prepared image gives the same text as recognizing the image itself
+func (p *PreparedImage) binaryFor(o *OCR) (*imageBinary, error) {
+	n := normalization{o.ContrastTile, o.BinaryThreshold, o.AlphaOnly}
+	if n == (normalization{}) {
+		return p.binary(), nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if bi, ok := p.normalized[n]; ok {
+		return bi, nil
+	}
+	normalized, err := o.normalize(grayScale(p.img, o.AlphaOnly))
+	if err != nil {
+		return nil, err
+	}
+	bi := newImageBinary(normalized)
+	bi.offset = p.img.Bounds().Min
+	if p.normalized == nil {
+		p.normalized = make(map[normalization]*imageBinary)
+	}
+	p.normalized[n] = bi
+	return bi, nil
+}
+
+// ScoreAt works like the ScoreAt function, reusing the index of the image.
+func (p *PreparedImage) ScoreAt(fs *FontSymbol, x, y int) (float64, error) {
+	bi := p.binary()
+	return scoreAt(bi, fs.image, x-bi.offset.X, y-bi.offset.Y)
+}
+
+// FindAllInRect searches for all occurrences of the symbol scoring at least threshold, with the
+// symbol fully inside the region r of the image. Positions are in the coordinates of the image.
+func (p *PreparedImage) FindAllInRect(fs *FontSymbol, r image.Rectangle, threshold float64) ([]GPoint, error) {
+	bi := p.binary()
+	rect, err := scanRect(p.img.Bounds(), r)
+	if err != nil {
+		return nil, err
+	}
+	found, err := lookupAll(bi, rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y, fs.image, threshold)
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		found[i].X += bi.offset.X
+		found[i].Y += bi.offset.Y
+	}
+	return found, nil
+}
+
+// RecognizePrepared works like Recognize, reusing the index of the prepared image. Use it to
+// recognize the same image with several OCRs, like ones with different fontsets or options,
+// building the index only once. OCRs normalizing the image, with a ContrastTile, a
+// BinaryThreshold or AlphaOnly, use an index of the normalized image, built once for each
+// normalization.
+func (o *OCR) RecognizePrepared(p *PreparedImage) (string, error) {
+	if err := o.checkImageSize(p.img.Bounds()); err != nil {
+		return "", err
+	}
+	bi, err := p.binaryFor(o)
+	if err != nil {
+		return "", err
+	}
+	return o.recognize(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols)
+}
+
+// RecognizePreparedRegion works like RecognizeRegion, reusing the index of the prepared image.
+// The region is in the coordinates of the image.
+func (o *OCR) RecognizePreparedRegion(p *PreparedImage, r image.Rectangle) (string, error) {
+	if err := o.checkImageSize(p.img.Bounds()); err != nil {
+		return "", err
+	}
+	rect, err := scanRect(p.img.Bounds(), r)
+	if err != nil {
+		return "", err
+	}
+	bi, err := p.binaryFor(o)
+	if err != nil {
+		return "", err
+	}
+	return o.recognize(context.Background(), bi, rect, o.allSymbols)
+}
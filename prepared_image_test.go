@@ -0,0 +1,123 @@
+package lookup
+
+import (
+	"image"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPreparedImage(t *testing.T) {
+	Convey("Given a prepared sub image", t, func() {
+		img := loadImageColor("testdata/full.png").(*image.NRGBA).SubImage(image.Rect(1280, 646, 1280+61, 646+31))
+		prepared := NewPreparedImage(img)
+		prepared.BuildIndex()
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+
+		Convey("It recognizes the text", func() {
+			text, err := ocr.RecognizePrepared(prepared)
+			So(err, ShouldBeNil)
+			So(text, ShouldEqual, "4339")
+		})
+
+		Convey("It recognizes regions in the coordinates of the image", func() {
+			text, err := ocr.RecognizePreparedRegion(prepared, image.Rect(1280, 646, 1310, 677))
+			So(err, ShouldBeNil)
+			expected, _ := ocr.RecognizeRegion(img, image.Rect(1280, 646, 1310, 677))
+			So(text, ShouldEqual, expected)
+			So(text, ShouldNotBeEmpty)
+
+			_, err = ocr.RecognizePreparedRegion(prepared, image.Rect(0, 0, 10, 10))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("It recognizes the same text with OCRs sharing the index", func() {
+			other := NewOCR(0.7, 2)
+			_ = other.LoadFont("testdata/font_1")
+			text, err := other.RecognizePrepared(prepared)
+			So(err, ShouldBeNil)
+			expected, _ := other.Recognize(img)
+			So(text, ShouldEqual, expected)
+		})
+
+		Convey("It normalizes the image like the OCR recognizing it does", func() {
+			normalizing := NewOCR(0.7)
+			_ = normalizing.LoadFont("testdata/font_1")
+			normalizing.BinaryThreshold = OtsuThreshold
+			text, err := normalizing.RecognizePrepared(prepared)
+			So(err, ShouldBeNil)
+			expected, _ := normalizing.Recognize(img)
+			So(text, ShouldEqual, expected)
+			normalized, _ := prepared.binaryFor(normalizing)
+			So(normalized, ShouldNotPointTo, prepared.binary())
+			again, _ := prepared.binaryFor(normalizing)
+			So(again, ShouldPointTo, normalized)
+			unnormalized, _ := prepared.binaryFor(ocr)
+			So(unnormalized, ShouldPointTo, prepared.binary())
+		})
+
+		Convey("It fails to normalize the image with an invalid binary threshold", func() {
+			ocr.BinaryThreshold = -2
+			_, err := ocr.RecognizePrepared(prepared)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("It finds the symbols in the coordinates of the image", func() {
+			matches, _ := ocr.RecognizeDetailed(img)
+			for _, m := range matches {
+				fs := symbolNamed(ocr, m.Symbol)
+				score, err := prepared.ScoreAt(fs, m.Rect.Min.X, m.Rect.Min.Y)
+				So(err, ShouldBeNil)
+				So(score, ShouldAlmostEqual, m.G)
+
+				found, err := prepared.FindAllInRect(fs, m.Rect.Inset(-1), 0.8)
+				So(err, ShouldBeNil)
+				So(found, ShouldContain, GPoint{m.Rect.Min.X, m.Rect.Min.Y, score})
+			}
+		})
+
+		Convey("It fails to find symbols outside the image", func() {
+			_, err := prepared.FindAllInRect(ocr.allSymbols[0], image.Rect(0, 0, 10, 10), 0.8)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestPreparedImageRegion(t *testing.T) {
+	Convey("Given a prepared image reused by several OCRs", t, func() {
+		img := loadImageColor("testdata/full.png")
+		prepared := NewPreparedImage(img)
+		r := image.Rect(1280, 646, 1280+61, 646+31)
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		normalizing := NewOCR(0.8)
+		_ = normalizing.LoadFont("testdata/font_1")
+		normalizing.ContrastTile = 16
+
+		Convey("It recognizes the text of a region like RecognizeRegion", func() {
+			for _, o := range []*OCR{ocr, normalizing} {
+				text, err := o.RecognizePreparedRegion(prepared, r)
+				So(err, ShouldBeNil)
+				expected, _ := o.RecognizeRegion(img, r)
+				So(text, ShouldEqual, expected)
+			}
+			text, _ := ocr.RecognizePreparedRegion(prepared, r)
+			So(text, ShouldEqual, "4339")
+		})
+
+		Convey("It fails for a region outside the image", func() {
+			_, err := ocr.RecognizePreparedRegion(prepared, image.Rect(5000, 5000, 5010, 5010))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func symbolNamed(ocr *OCR, symbol string) *FontSymbol {
+	for _, s := range ocr.allSymbols {
+		if s.symbol == symbol {
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package lookup
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"image"
+)
+
+// Result is the outcome of a recognition: the text read and the matches it was composed from.
+// It can be serialized with Marshal to be cached or transported, and restored with Unmarshal.
+type Result struct {
+	// The text recognized, as returned by Recognize without a Fallback, which is not used
+	Text string
+	// The symbols recognized, in reading order
+	Matches []Match
+}
+
+// Marshal encodes the result in a binary format that can be decoded by Unmarshal
+func (r *Result) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a result encoded by Marshal, replacing the contents of r
+func (r *Result) Unmarshal(data []byte) error {
+	var decoded Result
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&decoded); err != nil {
+		return err
+	}
+	*r = decoded
+	return nil
+}
+
+// RecognizeResult recognizes the text in the image, returning both the text and the
+// matches it was composed from
+func (o *OCR) RecognizeResult(img image.Image) (*Result, error) {
+	bi, err := o.prepare(img)
+	if err != nil {
+		return nil, err
+	}
+	all, err := o.detect(context.Background(), bi, image.Rect(0, 0, bi.width-1, bi.height-1), o.allSymbols, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Text: o.arrange(bi, all), Matches: toMatches(all, bi.offset)}, nil
+}
@@ -0,0 +1,36 @@
+package lookup
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResult(t *testing.T) {
+	Convey("Given a recognition result", t, func() {
+		ocr := NewOCR(0.8)
+		_ = ocr.LoadFont("testdata/font_1")
+		result, err := ocr.RecognizeResult(loadImageColor("testdata/test3.png"))
+		So(err, ShouldBeNil)
+
+		Convey("It has the same text and matches returned by the other methods", func() {
+			So(result.Text, ShouldEqual, "3662\n3 2€/€")
+			matches, _ := ocr.RecognizeDetailed(loadImageColor("testdata/test3.png"))
+			So(result.Matches, ShouldResemble, matches)
+		})
+
+		Convey("It can be marshaled and unmarshaled", func() {
+			data, err := result.Marshal()
+			So(err, ShouldBeNil)
+
+			var restored Result
+			So(restored.Unmarshal(data), ShouldBeNil)
+			So(restored, ShouldResemble, *result)
+		})
+
+		Convey("It fails to unmarshal invalid data", func() {
+			var restored Result
+			So(restored.Unmarshal([]byte("invalid")), ShouldNotBeNil)
+		})
+	})
+}
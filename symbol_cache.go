@@ -0,0 +1,102 @@
+package lookup
+
+import (
+	"bytes"
+	"container/list"
+	"hash/fnv"
+	"image"
+	"sync"
+)
+
+// symbolBinaries caches the imageBinaries of the images symbols are created from. It is disabled
+// until SetSymbolCacheSize is called
+var symbolBinaries = &binaryCache{entries: make(map[uint64]*list.Element), order: list.New()}
+
+// SetSymbolCacheSize enables a cache of the precomputed tables of the images symbols are created
+// from, keeping those of the last size different images. Symbols created from an image identical
+// to a cached one, like when loading the same fontset again, reuse its tables instead of
+// computing them. The cache is shared by the whole package and is safe for concurrent use.
+// Setting the size to zero, the default, disables and empties the cache.
+func SetSymbolCacheSize(size int) {
+	symbolBinaries.resize(size)
+}
+
+// binaryCache is a bounded cache of imageBinaries of gray scale images, keyed by their contents,
+// that discards the least recently used ones
+type binaryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[uint64]*list.Element
+	order   *list.List
+}
+
+type binaryCacheEntry struct {
+	key   uint64
+	width int
+	pix   []byte
+	bi    *imageBinary
+}
+
+func (c *binaryCache) resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = max(size, 0)
+	for c.order.Len() > c.size {
+		c.evictOldest()
+	}
+}
+
+func (c *binaryCache) evictOldest() {
+	e := c.order.Back()
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*binaryCacheEntry).key)
+}
+
+// binary returns the imageBinary of the gray scale image, from the cache if an identical image
+// was cached. Images are cached only when the cache is enabled
+func (c *binaryCache) binary(img *image.Gray) *imageBinary {
+	c.mu.Lock()
+	enabled := c.size > 0
+	c.mu.Unlock()
+	if !enabled {
+		return newImageBinary(img)
+	}
+
+	img = grayAtOrigin(img)
+	h := fnv.New64a()
+	_, _ = h.Write(img.Pix)
+	key := h.Sum64() ^ uint64(img.Rect.Dx())
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*binaryCacheEntry)
+		if entry.width == img.Rect.Dx() && bytes.Equal(entry.pix, img.Pix) {
+			c.order.MoveToFront(e)
+			c.mu.Unlock()
+			return entry.bi
+		}
+	}
+	c.mu.Unlock()
+
+	bi := newImageBinary(img)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size == 0 {
+		return bi
+	}
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+	}
+	c.entries[key] = c.order.PushFront(&binaryCacheEntry{key: key, width: img.Rect.Dx(), pix: append([]byte(nil), img.Pix...), bi: bi})
+	for c.order.Len() > c.size {
+		c.evictOldest()
+	}
+	return bi
+}
+
+// len returns the number of images cached
+func (c *binaryCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,52 @@
+package lookup
+
+import (
+	"image"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSymbolCache(t *testing.T) {
+	Convey("Given an image of a symbol", t, func() {
+		img := loadImageGray("testdata/font_1/3.png")
+		defer SetSymbolCacheSize(0)
+
+		Convey("It computes the tables of each symbol by default", func() {
+			So(NewFontSymbol("3", img).image, ShouldNotPointTo, NewFontSymbol("3", img).image)
+			So(symbolBinaries.len(), ShouldEqual, 0)
+		})
+
+		Convey("When the cache is enabled", func() {
+			SetSymbolCacheSize(2)
+
+			Convey("It reuses the tables of identical images", func() {
+				a := NewFontSymbol("3", img)
+				b := NewFontSymbol("3", loadImageGray("testdata/font_1/3.png"))
+				So(b.image, ShouldPointTo, a.image)
+				So(NewFontSymbol("8", loadImageGray("testdata/font_1/8.png")).image, ShouldNotPointTo, a.image)
+			})
+
+			Convey("It recognizes the text with the cached tables", func() {
+				ocr := NewOCR(0.8)
+				_ = ocr.LoadFont("testdata/font_1")
+				_ = ocr.LoadFont("testdata/font_1")
+				text, _ := ocr.Recognize(loadImageColor("testdata/test3.png"))
+				So(text, ShouldEqual, "3662\n3 2€/€")
+			})
+
+			Convey("It keeps only the most recently used images", func() {
+				for _, size := range []int{4, 5, 6} {
+					NewFontSymbol("x", image.NewGray(image.Rect(0, 0, size, size)))
+				}
+				So(symbolBinaries.len(), ShouldEqual, 2)
+			})
+
+			Convey("It empties the cache when disabled", func() {
+				NewFontSymbol("3", img)
+				SetSymbolCacheSize(0)
+				So(symbolBinaries.len(), ShouldEqual, 0)
+			})
+		})
+	})
+}
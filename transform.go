@@ -0,0 +1,282 @@
+package lookup
+
+import (
+	"image"
+	"math"
+)
+
+// flipHorizontal returns a mirrored copy of the image, as seen in a mirror placed at its side
+func flipHorizontal(img *image.Gray) *image.Gray {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	flipped := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		src := img.Pix[y*img.Stride : y*img.Stride+w]
+		dst := flipped.Pix[y*flipped.Stride : y*flipped.Stride+w]
+		for x := range src {
+			dst[w-1-x] = src[x]
+		}
+	}
+	return flipped
+}
+
+// invert returns the negative of the image
+func invert(img *image.Gray) *image.Gray {
+	inverted := image.NewGray(img.Bounds())
+	for i, v := range img.Pix {
+		inverted.Pix[i] = 255 - v
+	}
+	return inverted
+}
+
+// boxBlur smooths the image, replacing each pixel by the mean of the pixels within the radius
+func boxBlur(img *image.Gray, radius int) *image.Gray {
+	integral := newIntegralImage(img)
+	w, h := integral.width, integral.height
+	blurred := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			x1, y1 := max(x-radius, 0), max(y-radius, 0)
+			x2, y2 := min(x+radius, w-1), min(y+radius, h-1)
+			size := float64((x2 - x1 + 1) * (y2 - y1 + 1))
+			blurred.Pix[y*w+x] = uint8(math.Round(integral.sigma(integral.pix, x1, y1, x2, y2) / size))
+		}
+	}
+	return blurred
+}
+
+// adaptiveThreshold binarizes the image comparing each pixel to the mean of its neighbourhood
+// (within the radius). Pixels darker than the mean by more than offset become black, all others
+// white. Unlike a global threshold, this copes with uneven lighting.
+func adaptiveThreshold(img *image.Gray, radius int, offset float64) *image.Gray {
+	mean := boxBlur(img, radius)
+	binary := image.NewGray(mean.Bounds())
+	for i, v := range mean.Pix {
+		if float64(img.Pix[i]) < float64(v)-offset {
+			binary.Pix[i] = 0
+		} else {
+			binary.Pix[i] = 255
+		}
+	}
+	return binary
+}
+
+// binarize returns the image with the pixels at least as bright as the threshold made white, and
+// the rest black
+func binarize(img *image.Gray, threshold int) *image.Gray {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	binary := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x, v := range img.Pix[y*img.Stride : y*img.Stride+w] {
+			if int(v) >= threshold {
+				binary.Pix[y*binary.Stride+x] = 255
+			}
+		}
+	}
+	return binary
+}
+
+// otsuThreshold picks the threshold that best separates the pixels of the image in two classes,
+// maximizing the variance between them (Otsu's method). Pixels at least as bright as it are in
+// the brighter class
+func otsuThreshold(img *image.Gray) int {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var histogram [256]int
+	sum := 0.0
+	for y := 0; y < h; y++ {
+		for _, v := range img.Pix[y*img.Stride : y*img.Stride+w] {
+			histogram[v]++
+			sum += float64(v)
+		}
+	}
+
+	total := float64(w * h)
+	best, bestVariance := 0, -1.0
+	darkCount, darkSum := 0.0, 0.0
+	for t := 1; t < 256; t++ {
+		// pixels below t are in the darker class
+		darkCount += float64(histogram[t-1])
+		darkSum += float64(t-1) * float64(histogram[t-1])
+		brightCount := total - darkCount
+		if darkCount == 0 || brightCount == 0 {
+			continue
+		}
+		diff := darkSum/darkCount - (sum-darkSum)/brightCount
+		if variance := darkCount * brightCount * diff * diff; variance > bestVariance {
+			best, bestVariance = t, variance
+		}
+	}
+	return best
+}
+
+// scale resizes the image by the given factors, using bilinear interpolation
+func scale(img *image.Gray, sx, sy float64) *image.Gray {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	sw, sh := max(int(math.Round(float64(w)*sx)), 1), max(int(math.Round(float64(h)*sy)), 1)
+	scaled := image.NewGray(image.Rect(0, 0, sw, sh))
+	at := func(x, y int) float64 {
+		x, y = min(max(x, 0), w-1), min(max(y, 0), h-1)
+		return float64(img.Pix[y*img.Stride+x])
+	}
+	for y := 0; y < sh; y++ {
+		fy := (float64(y)+0.5)*float64(h)/float64(sh) - 0.5
+		y0 := int(math.Floor(fy))
+		dy := fy - float64(y0)
+		for x := 0; x < sw; x++ {
+			fx := (float64(x)+0.5)*float64(w)/float64(sw) - 0.5
+			x0 := int(math.Floor(fx))
+			dx := fx - float64(x0)
+			top := at(x0, y0)*(1-dx) + at(x0+1, y0)*dx
+			bottom := at(x0, y0+1)*(1-dx) + at(x0+1, y0+1)*dx
+			scaled.Pix[y*scaled.Stride+x] = uint8(math.Round(top*(1-dy) + bottom*dy))
+		}
+	}
+	return scaled
+}
+
+// rotate90 returns the image rotated clockwise by 90 degrees
+func rotate90(img *image.Gray) *image.Gray {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	rotated := image.NewGray(image.Rect(0, 0, h, w))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			rotated.Pix[x*rotated.Stride+h-1-y] = img.Pix[y*img.Stride+x]
+		}
+	}
+	return rotated
+}
+
+// rotate returns the image rotated clockwise by the given degrees around its center, interpolating
+// bilinearly. The size of the image is kept, and the uncovered corners are filled with the most
+// common value of the image, taken as its background
+func rotate(img *image.Gray, degrees float64) *image.Gray {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var histogram [256]int
+	for y := 0; y < h; y++ {
+		for _, v := range img.Pix[y*img.Stride : y*img.Stride+w] {
+			histogram[v]++
+		}
+	}
+	background := 0
+	for v, n := range histogram {
+		if n > histogram[background] {
+			background = v
+		}
+	}
+
+	rotated := image.NewGray(image.Rect(0, 0, w, h))
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return float64(background)
+		}
+		return float64(img.Pix[y*img.Stride+x])
+	}
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	cx, cy := float64(w-1)/2, float64(h-1)/2
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// the pixel of the source image that ends up at (x, y)
+			fx := cos*(float64(x)-cx) + sin*(float64(y)-cy) + cx
+			fy := -sin*(float64(x)-cx) + cos*(float64(y)-cy) + cy
+			x0, y0 := int(math.Floor(fx)), int(math.Floor(fy))
+			tx, ty := fx-float64(x0), fy-float64(y0)
+			top := at(x0, y0)*(1-tx) + at(x0+1, y0)*tx
+			bottom := at(x0, y0+1)*(1-tx) + at(x0+1, y0+1)*tx
+			rotated.Pix[y*rotated.Stride+x] = uint8(math.Round(top*(1-ty) + bottom*ty))
+		}
+	}
+	return rotated
+}
+
+// shift returns the image moved by the given fraction of pixels, interpolating bilinearly.
+// The size of the image is kept, repeating the border pixels in the uncovered area
+func shift(img *image.Gray, dx, dy float64) *image.Gray {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	shifted := image.NewGray(image.Rect(0, 0, w, h))
+	at := func(x, y int) float64 {
+		x, y = min(max(x, 0), w-1), min(max(y, 0), h-1)
+		return float64(img.Pix[y*img.Stride+x])
+	}
+	for y := 0; y < h; y++ {
+		fy := float64(y) - dy
+		y0 := int(math.Floor(fy))
+		ty := fy - float64(y0)
+		for x := 0; x < w; x++ {
+			fx := float64(x) - dx
+			x0 := int(math.Floor(fx))
+			tx := fx - float64(x0)
+			top := at(x0, y0)*(1-tx) + at(x0+1, y0)*tx
+			bottom := at(x0, y0+1)*(1-tx) + at(x0+1, y0+1)*tx
+			shifted.Pix[y*shifted.Stride+x] = uint8(math.Round(top*(1-ty) + bottom*ty))
+		}
+	}
+	return shifted
+}
+
+// claheClipLimit limits how much contrast equalizeContrast can add, as a multiple of the mean
+// count of the histogram bins. Without it, noise in uniform areas would be amplified
+const claheClipLimit = 4
+
+// equalizeContrast normalizes the contrast of the image locally, using Contrast Limited
+// Adaptive Histogram Equalization (CLAHE). The histogram of each tile of the given size is
+// equalized, and pixels are mapped interpolating the mappings of the closest tiles.
+func equalizeContrast(img *image.Gray, tile int) *image.Gray {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	cols, rows := (w+tile-1)/tile, (h+tile-1)/tile
+
+	// mapping of each gray level in each tile
+	luts := make([][256]uint8, cols*rows)
+	for ty := 0; ty < rows; ty++ {
+		for tx := 0; tx < cols; tx++ {
+			x1, y1 := tx*tile, ty*tile
+			x2, y2 := min(x1+tile, w), min(y1+tile, h)
+			var histogram [256]int
+			for y := y1; y < y2; y++ {
+				for x := x1; x < x2; x++ {
+					histogram[img.Pix[y*img.Stride+x]]++
+				}
+			}
+			size := (x2 - x1) * (y2 - y1)
+			limit := max(claheClipLimit*size/256, 1)
+			excess := 0
+			for i, n := range histogram {
+				if n > limit {
+					excess += n - limit
+					histogram[i] = limit
+				}
+			}
+			sum := 0
+			for i, n := range histogram {
+				sum += n + excess/256
+				if i < excess%256 {
+					sum++
+				}
+				luts[ty*cols+tx][i] = uint8(math.Round(float64(sum) * 255 / float64(size)))
+			}
+		}
+	}
+
+	equalized := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		// position relative to the centers of the tiles
+		fy := float64(y)/float64(tile) - 0.5
+		ty := int(math.Floor(fy))
+		dy := fy - float64(ty)
+		ty1, ty2 := min(max(ty, 0), rows-1), min(max(ty+1, 0), rows-1)
+		for x := 0; x < w; x++ {
+			fx := float64(x)/float64(tile) - 0.5
+			tx := int(math.Floor(fx))
+			dx := fx - float64(tx)
+			tx1, tx2 := min(max(tx, 0), cols-1), min(max(tx+1, 0), cols-1)
+			v := img.Pix[y*img.Stride+x]
+			top := float64(luts[ty1*cols+tx1][v])*(1-dx) + float64(luts[ty1*cols+tx2][v])*dx
+			bottom := float64(luts[ty2*cols+tx1][v])*(1-dx) + float64(luts[ty2*cols+tx2][v])*dx
+			equalized.Pix[y*equalized.Stride+x] = uint8(math.Round(top*(1-dy) + bottom*dy))
+		}
+	}
+	return equalized
+}